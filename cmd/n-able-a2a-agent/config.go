@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"fusion/internal/a2a"
+	"fusion/internal/llm"
+	"fusion/internal/tools"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileConfig is the layout of the file given with -config. Settings missing from the file keep their
+// defaults, environment variables override the file and flags override both.
+type fileConfig struct {
+	Model           llm.Config        `json:"model"`
+	Limits          fileLimits        `json:"limits"`
+	Context         a2a.ContextLimits `json:"context"`
+	QueryPolicy     tools.QueryPolicy `json:"queryPolicy"`
+	Card            a2a.CardConfig    `json:"card"`
+	ExposeReasoning bool              `json:"exposeReasoning"`
+}
+
+type fileLimits struct {
+	MaxTurns         int    `json:"maxTurns"`
+	MaxInputTokens   int    `json:"maxInputTokens"`
+	MaxOutputTokens  int    `json:"maxOutputTokens"`
+	MaxDuration      string `json:"maxDuration"`
+	MaxParallelTools int    `json:"maxParallelTools"`
+}
+
+func loadConfigFile(path string, config *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	file := fileConfig{
+		Model:           config.Model,
+		Context:         config.ContextLimits,
+		QueryPolicy:     config.QueryPolicy,
+		Card:            config.Card,
+		ExposeReasoning: config.ExposeReasoning,
+		Limits: fileLimits{
+			MaxTurns:         config.Limits.MaxTurns,
+			MaxInputTokens:   config.Limits.MaxInputTokens,
+			MaxOutputTokens:  config.Limits.MaxOutputTokens,
+			MaxParallelTools: config.Limits.MaxParallelTools,
+		},
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	config.Model = file.Model
+	config.ContextLimits = file.Context
+	config.QueryPolicy = file.QueryPolicy
+	config.Card = file.Card
+	config.ExposeReasoning = file.ExposeReasoning
+	config.Limits.MaxTurns = file.Limits.MaxTurns
+	config.Limits.MaxInputTokens = file.Limits.MaxInputTokens
+	config.Limits.MaxOutputTokens = file.Limits.MaxOutputTokens
+	config.Limits.MaxParallelTools = file.Limits.MaxParallelTools
+	if file.Limits.MaxDuration != "" {
+		config.Limits.MaxDuration, err = time.ParseDuration(file.Limits.MaxDuration)
+		if err != nil {
+			return fmt.Errorf("invalid maxDuration in config file %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func applyEnvironment(config *Config) error {
+	model := &config.Model
+	stringEnv("AGENT_PROVIDER", &model.Provider)
+	stringEnv("AGENT_MODEL", &model.Model)
+	stringEnv("AGENT_REGION", &model.Region)
+	stringEnv("AGENT_PROFILE", &model.Profile)
+	stringEnv("AGENT_OPENAI_ENDPOINT", &model.Endpoint)
+
+	if value, ok := os.LookupEnv("AGENT_TEMPERATURE"); ok {
+		temperature, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return fmt.Errorf("invalid AGENT_TEMPERATURE: %w", err)
+		}
+		model.Temperature = float32(temperature)
+	}
+	if value, ok := os.LookupEnv("AGENT_MAX_TOKENS"); ok {
+		maxTokens, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid AGENT_MAX_TOKENS: %w", err)
+		}
+		model.MaxTokens = maxTokens
+	}
+	return nil
+}
+
+func stringEnv(name string, value *string) {
+	if v, ok := os.LookupEnv(name); ok {
+		*value = v
+	}
+}
+
+// overrideFlags holds the flags for settings that can also be in the config file until the file and
+// environment have been read, so that only the flags given on the command line override them.
+type overrideFlags struct {
+	model           llm.Config
+	temperature     float64
+	limits          a2a.LoopLimits
+	context         a2a.ContextLimits
+	queryPolicy     tools.QueryPolicy
+	allowMutations  string
+	card            a2a.CardConfig
+	exposeReasoning bool
+}
+
+func (f *overrideFlags) register(defaults Config) {
+	f.registerModel(defaults.Model)
+	flag.IntVar(&f.limits.MaxTurns, "max-turns", defaults.Limits.MaxTurns, "Maximum number of model turns per task, 0 for no limit")
+	flag.IntVar(&f.limits.MaxInputTokens, "max-input-tokens", defaults.Limits.MaxInputTokens, "Maximum number of input tokens used per task, 0 for no limit")
+	flag.IntVar(&f.limits.MaxOutputTokens, "max-output-tokens", defaults.Limits.MaxOutputTokens, "Maximum number of output tokens generated per task, 0 for no limit")
+	flag.DurationVar(&f.limits.MaxDuration, "max-duration", defaults.Limits.MaxDuration, "Maximum time a task may run, 0 for no limit")
+	flag.IntVar(&f.limits.MaxParallelTools, "max-parallel-tools", defaults.Limits.MaxParallelTools, "Maximum number of tool calls of one model turn run at the same time, 0 for no limit")
+	flag.IntVar(&f.context.MaxResultTokens, "max-result-tokens", defaults.ContextLimits.MaxResultTokens, "Estimated tokens above which a tool result is truncated, 0 for no limit")
+	flag.IntVar(&f.context.SummaryThreshold, "summary-threshold", defaults.ContextLimits.SummaryThreshold, "Estimated tokens of conversation above which older turns are summarised, 0 to never summarise")
+	flag.IntVar(&f.context.KeepRecentTokens, "keep-recent-tokens", defaults.ContextLimits.KeepRecentTokens, "Estimated tokens of recent conversation kept as it is when summarising")
+	flag.IntVar(&f.queryPolicy.MaxDepth, "max-query-depth", defaults.QueryPolicy.MaxDepth, "Maximum nesting of fields in a query written by the model, 0 for no limit")
+	flag.IntVar(&f.queryPolicy.MaxComplexity, "max-query-complexity", defaults.QueryPolicy.MaxComplexity, "Maximum number of fields a query written by the model may return, 0 for no limit")
+	flag.IntVar(&f.queryPolicy.MaxFirst, "max-query-first", defaults.QueryPolicy.MaxFirst, "Maximum number of items a field of a query written by the model may ask for with first, 0 for no limit")
+	flag.StringVar(&f.allowMutations, "allow-mutations", strings.Join(defaults.QueryPolicy.AllowedMutations, ","), "Comma separated mutations the model may run. Mutations are denied when empty")
+	flag.StringVar(&f.card.URL, "public-url", defaults.Card.URL, "URL clients reach the agent at, published on the agent card")
+	flag.StringVar(&f.card.Version, "agent-version", defaults.Card.Version, "Version published on the agent card. The version of the build is used when empty")
+	flag.BoolVar(&f.exposeReasoning, "expose-reasoning", defaults.ExposeReasoning, "Send the model's reasoning to clients as a separate artifact")
+}
+
+func (f *overrideFlags) registerModel(defaults llm.Config) {
+	flag.StringVar(&f.model.Provider, "provider", defaults.Provider, "Model provider: bedrock or openai for an OpenAI compatible server")
+	flag.StringVar(&f.model.Model, "model", defaults.Model, "Model ID. Defaults to "+llm.DefaultBedrockModel+" on bedrock")
+	flag.StringVar(&f.model.Region, "region", defaults.Region, "AWS region of the Bedrock model")
+	flag.StringVar(&f.model.Profile, "profile", defaults.Profile, "AWS shared config profile. The default credential chain is used when empty")
+	flag.StringVar(&f.model.Endpoint, "openai-endpoint", defaults.Endpoint, "Base URL of the OpenAI compatible server")
+	flag.Float64Var(&f.temperature, "temperature", float64(defaults.Temperature), "Model temperature")
+	flag.IntVar(&f.model.MaxTokens, "max-tokens", defaults.MaxTokens, "Maximum number of tokens the model generates per turn")
+}
+
+func (f *overrideFlags) apply(config *Config) {
+	model := &config.Model
+	flag.Visit(func(set *flag.Flag) {
+		switch set.Name {
+		case "provider":
+			model.Provider = f.model.Provider
+		case "model":
+			model.Model = f.model.Model
+		case "region":
+			model.Region = f.model.Region
+		case "profile":
+			model.Profile = f.model.Profile
+		case "openai-endpoint":
+			model.Endpoint = f.model.Endpoint
+		case "temperature":
+			model.Temperature = float32(f.temperature)
+		case "max-tokens":
+			model.MaxTokens = f.model.MaxTokens
+		case "max-turns":
+			config.Limits.MaxTurns = f.limits.MaxTurns
+		case "max-input-tokens":
+			config.Limits.MaxInputTokens = f.limits.MaxInputTokens
+		case "max-output-tokens":
+			config.Limits.MaxOutputTokens = f.limits.MaxOutputTokens
+		case "max-duration":
+			config.Limits.MaxDuration = f.limits.MaxDuration
+		case "max-parallel-tools":
+			config.Limits.MaxParallelTools = f.limits.MaxParallelTools
+		case "max-result-tokens":
+			config.ContextLimits.MaxResultTokens = f.context.MaxResultTokens
+		case "summary-threshold":
+			config.ContextLimits.SummaryThreshold = f.context.SummaryThreshold
+		case "keep-recent-tokens":
+			config.ContextLimits.KeepRecentTokens = f.context.KeepRecentTokens
+		case "max-query-depth":
+			config.QueryPolicy.MaxDepth = f.queryPolicy.MaxDepth
+		case "max-query-complexity":
+			config.QueryPolicy.MaxComplexity = f.queryPolicy.MaxComplexity
+		case "max-query-first":
+			config.QueryPolicy.MaxFirst = f.queryPolicy.MaxFirst
+		case "allow-mutations":
+			config.QueryPolicy.AllowedMutations = nil
+			for _, mutation := range strings.Split(f.allowMutations, ",") {
+				if mutation = strings.TrimSpace(mutation); mutation != "" {
+					config.QueryPolicy.AllowedMutations = append(config.QueryPolicy.AllowedMutations, mutation)
+				}
+			}
+		case "public-url":
+			config.Card.URL = f.card.URL
+		case "agent-version":
+			config.Card.Version = f.card.Version
+		case "expose-reasoning":
+			config.ExposeReasoning = f.exposeReasoning
+		}
+	})
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"fusion/internal/a2a"
+	"fusion/internal/knowledge"
+	"fusion/internal/llm"
+	"fusion/internal/tools"
 	"github.com/redis/go-redis/v9"
 	"log"
 	"os"
@@ -11,26 +15,47 @@ import (
 	"syscall"
 	"time"
 
+	"trpc.group/trpc-go/trpc-a2a-go/auth"
+	"trpc.group/trpc-go/trpc-a2a-go/protocol"
 	"trpc.group/trpc-go/trpc-a2a-go/server"
 	redisTaskManager "trpc.group/trpc-go/trpc-a2a-go/taskmanager/redis"
 )
 
 type Config struct {
-	Token     string
-	ContextID string
+	ContextID       string
+	AuthKeys        string
+	AuthJWKS        string
+	AuthIssuer      string
+	AuthAudience    string
+	UnscopedCallers bool
+	SchemaCacheDir  string
+	SchemaTTL       time.Duration
+	Endpoint        string
+	QueryTimeout    time.Duration
+	Pagination      tools.PaginationLimits
+	QueryPolicy     tools.QueryPolicy
+	AuditLog        string
+	KnowledgeDir    string
+	KnowledgeIndex  string
+	ConfigFile      string
+	Model           llm.Config
+	Limits          a2a.LoopLimits
+	ExposeReasoning bool
+	TranscriptTTL   time.Duration
+	ContextLimits   a2a.ContextLimits
+	PushRetry       a2a.PushRetry
+	PrivateWebhooks bool
+	PushDeliveryTTL time.Duration
+	TaskOwnerTTL    time.Duration
+	Card            a2a.CardConfig
 }
 
 func main() {
 
 	config := parseFlags()
 
-	fmt.Printf("Configuration => Token: %s, Context: %s\n", config.Token, config.ContextID)
-
-	agentCard := a2a.GetAgentCard()
-	processor, err := a2a.NewAgent(config.Token)
-	if err != nil {
-		log.Fatalf("Failed to create agent: %v", err)
-	}
+	fmt.Printf("Configuration => Context: %s\n", config.ContextID)
+	fmt.Printf("Model => Provider: %s, Model: %s, Temperature: %g, Max tokens: %d\n", config.Model.Provider, config.Model.Model, config.Model.Temperature, config.Model.MaxTokens)
 
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     "localhost:6379",
@@ -38,18 +63,98 @@ func main() {
 		DB:       0,
 	})
 
+	var schemaCache tools.SchemaCache = tools.NewRedisSchemaCache(redisClient)
+	if config.SchemaCacheDir != "" {
+		schemaCache = tools.NewFileSchemaCache(config.SchemaCacheDir)
+	}
+	graphQLClient := tools.NewGraphQLClient(config.Endpoint, tools.WithQueryTimeout(config.QueryTimeout))
+	schemas := tools.NewSchemaProvider(graphQLClient, schemaCache, config.SchemaTTL)
+
+	knowledgeIndex, err := knowledge.OpenIndex(config.KnowledgeIndex, config.KnowledgeDir)
+	if err != nil {
+		log.Printf("Knowledge base unavailable: %v", err)
+	} else {
+		fmt.Printf("Knowledge base loaded with %d articles\n", len(knowledgeIndex.Articles))
+	}
+
+	var transcripts a2a.TranscriptStore
+	if config.TranscriptTTL > 0 {
+		transcripts = a2a.NewRedisTranscriptStore(redisClient, config.TranscriptTTL)
+	}
+
+	// Truncated tool results are kept as long as the transcripts that refer to them.
+	var results tools.ResultStore
+	if config.ContextLimits.MaxResultTokens > 0 {
+		resultTTL := config.TranscriptTTL
+		if resultTTL <= 0 {
+			resultTTL = time.Hour
+		}
+		results = tools.NewRedisResultStore(redisClient, resultTTL)
+	}
+
+	model, err := llm.NewModel(context.Background(), config.Model)
+	if err != nil {
+		log.Fatalf("Failed to create model: %v", err)
+	}
+
+	authenticator, err := newAuthenticator(config)
+	if err != nil {
+		log.Fatalf("Failed to create authenticator: %v", err)
+	}
+
+	// Denied operations are audited to stdout unless an audit log is given.
+	auditLog := os.Stdout
+	if config.AuditLog != "" {
+		auditLog, err = os.OpenFile(config.AuditLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			log.Fatalf("Failed to open audit log: %v", err)
+		}
+		defer auditLog.Close()
+	}
+	queryPolicy := config.QueryPolicy
+	queryPolicy.Audit = tools.NewJSONPolicyAuditor(auditLog)
+	fmt.Printf("Query Policy => Max depth: %d, Max complexity: %d, Max first: %d, Allowed mutations: %v\n", queryPolicy.MaxDepth, queryPolicy.MaxComplexity, queryPolicy.MaxFirst, queryPolicy.AllowedMutations)
+
+	processor, err := a2a.NewAgent(model, config.Model, config.Limits, config.ExposeReasoning, transcripts, config.ContextLimits, results, graphQLClient, schemas, config.Pagination, &queryPolicy, knowledgeIndex)
+	if err != nil {
+		log.Fatalf("Failed to create agent: %v", err)
+	}
+	processor.UnscopedCallers = config.UnscopedCallers
+	taskOwners := a2a.NewTaskOwners(redisClient, config.TaskOwnerTTL)
+	processor.Owners = taskOwners
+
+	config.Card.SecuritySchemes = securitySchemes(config)
+	agentCard, err := a2a.NewAgentCard(processor.Registry, config.Card)
+	if err != nil {
+		log.Fatalf("Failed to create agent card: %v", err)
+	}
+	fmt.Printf("Agent Card => URL: %s, Version: %s, Skills: %d\n", agentCard.URL, agentCard.Version, len(agentCard.Skills))
+
+	// Push notifications are signed with a key published at the JWKS endpoint of the server.
+	pushSigner := auth.NewPushNotificationAuthenticator()
+	if err := pushSigner.GenerateKeyPair(); err != nil {
+		log.Fatalf("Failed to create push notification key: %v", err)
+	}
+	pushNotifier := a2a.NewPushNotifier(pushSigner, redisClient, config.PushDeliveryTTL, config.PushRetry)
+	pushNotifier.PrivateWebhooks = config.PrivateWebhooks
+	processor.Push = pushNotifier
+
 	taskManager, err := redisTaskManager.NewTaskManager(redisClient, processor)
 
 	if err != nil {
 		log.Fatalf("Failed to create task manager: %v", err)
 	}
+	pushNotifier.Tasks = taskManager
 
 	options := []server.Option{
 		server.WithIdleTimeout(300 * time.Second),
 		server.WithReadTimeout(300 * time.Second),
 		server.WithWriteTimeout(300 * time.Second),
+		server.WithAuthProvider(authenticator),
+		server.WithJWKSEndpoint(true, protocol.JWKSPath),
+		server.WithPushNotificationAuthenticator(pushSigner),
 	}
-	srv, err := server.NewA2AServer(agentCard, taskManager, options...)
+	srv, err := server.NewA2AServer(agentCard, a2a.NewPushTaskManager(a2a.NewCancellingTaskManager(a2a.NewOwnedTaskManager(taskManager, taskOwners), processor, taskOwners), pushNotifier, taskOwners), options...)
 	if err != nil {
 		log.Fatalf("Failed to create server: %v", err)
 	}
@@ -67,15 +172,97 @@ func main() {
 	log.Printf("Received signal %v, shutting down...", sig)
 }
 
-func stringPtr(s string) *string {
-	return &s
+// newAuthenticator returns the authenticator of A2A requests. A caller is accepted when any of the
+// configured authenticators accepts it.
+func newAuthenticator(config Config) (auth.Provider, error) {
+	var authenticators []auth.Provider
+	if config.AuthKeys != "" {
+		keys, err := a2a.NewStaticKeyAuthenticator(config.AuthKeys)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Authentication => %d static keys\n", len(keys.Keys))
+		authenticators = append(authenticators, keys)
+	}
+	if config.AuthJWKS != "" {
+		jwks, err := a2a.NewJWKSAuthenticator(config.AuthJWKS, config.AuthIssuer, config.AuthAudience)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Authentication => JWTs signed with %d keys, Issuer: %s, Audience: %s\n", jwks.Keys.Len(), config.AuthIssuer, config.AuthAudience)
+		authenticators = append(authenticators, jwks)
+	}
+	if len(authenticators) == 0 {
+		return nil, fmt.Errorf("set -auth-keys or -auth-jwks")
+	}
+	return a2a.NewAuthenticator(authenticators...)
+}
+
+// securitySchemes describes the authenticators of newAuthenticator on the agent card.
+func securitySchemes(config Config) map[string]server.SecurityScheme {
+	schemes := map[string]server.SecurityScheme{}
+	if config.AuthKeys != "" {
+		schemes["bearer"] = server.SecurityScheme{
+			Type:        server.SecuritySchemeTypeHTTP,
+			Scheme:      llm.StringPtr("bearer"),
+			Description: llm.StringPtr("A static key issued to the caller"),
+		}
+	}
+	if config.AuthJWKS != "" {
+		schemes["jwt"] = server.SecurityScheme{
+			Type:         server.SecuritySchemeTypeHTTP,
+			Scheme:       llm.StringPtr("bearer"),
+			BearerFormat: llm.StringPtr("JWT"),
+			Description:  llm.StringPtr("A JWT whose subject is the caller and whose organizations claim lists the organizations it may see"),
+		}
+	}
+	return schemes
 }
 
 func parseFlags() Config {
 	var config Config
 
-	flag.StringVar(&config.Token, "token", "", "User SSO Token")
+	flag.StringVar(&config.AuthKeys, "auth-keys", "", "JSON file mapping each caller to the bearer token it authenticates with, or to an object with its token and organizations")
+	flag.StringVar(&config.AuthJWKS, "auth-jwks", "", "JWKS file of the keys that sign the JWTs callers authenticate with")
+	flag.StringVar(&config.AuthIssuer, "auth-issuer", "", "Required issuer of caller JWTs")
+	flag.StringVar(&config.AuthAudience, "auth-audience", "", "Required audience of caller JWTs")
+	flag.BoolVar(&config.UnscopedCallers, "unscoped-callers", false, "Let callers without an organizations claim see the assets of every organization. They are denied otherwise")
+	flag.StringVar(&config.Endpoint, "endpoint", "staging", "GraphQL endpoint: prod, staging or the URL of another server such as a local stub")
+	flag.DurationVar(&config.QueryTimeout, "query-timeout", 10*time.Second, "Timeout for each GraphQL query")
+	flag.StringVar(&config.SchemaCacheDir, "schema-cache-dir", "", "Directory to cache the introspected schema in. Redis is used when not set")
+	flag.DurationVar(&config.SchemaTTL, "schema-ttl", time.Hour, "How long an introspected schema is cached for")
+	flag.IntVar(&config.Pagination.MaxPages, "max-pages", tools.DefaultPaginationLimits.MaxPages, "Maximum number of pages fetched by a paginated query, 0 for no limit")
+	flag.IntVar(&config.Pagination.MaxItems, "max-items", tools.DefaultPaginationLimits.MaxItems, "Maximum number of items fetched by a paginated query, 0 for no limit")
+	flag.StringVar(&config.AuditLog, "audit-log", "", "File the operations denied by the query policy are appended to as JSON lines. They are written to stdout when not set")
+	flag.StringVar(&config.KnowledgeDir, "knowledge-dir", "knowledge/articles", "Directory of Markdown and JSON knowledge articles")
+	flag.StringVar(&config.KnowledgeIndex, "knowledge-index", "knowledge/index.json", "Path of the knowledge index. It is built from the articles when missing")
+	flag.DurationVar(&config.TranscriptTTL, "transcript-ttl", 24*time.Hour, "How long the conversation of a context is kept for follow-up questions, 0 to not keep it")
+	flag.IntVar(&config.PushRetry.MaxAttempts, "push-attempts", a2a.DefaultPushRetry.MaxAttempts, "Number of times a push notification is sent before it is given up")
+	flag.DurationVar(&config.PushRetry.InitialBackoff, "push-backoff", a2a.DefaultPushRetry.InitialBackoff, "Wait before the first retry of a push notification. It doubles with every retry")
+	flag.DurationVar(&config.PushRetry.MaxBackoff, "push-max-backoff", a2a.DefaultPushRetry.MaxBackoff, "Longest wait between retries of a push notification")
+	flag.DurationVar(&config.TaskOwnerTTL, "task-owner-ttl", 24*time.Hour, "How long the caller who created a task is kept. Only that caller can act on the task, and no one can after it")
+	flag.BoolVar(&config.PrivateWebhooks, "push-private-webhooks", false, "Allow push notification webhooks on loopback, link-local and private addresses, for trying notifications locally")
+	flag.DurationVar(&config.PushDeliveryTTL, "push-delivery-ttl", 24*time.Hour, "How long the delivery state of a task's push notifications is kept")
+	flag.StringVar(&config.ConfigFile, "config", "", "JSON config file. Environment variables and flags override its settings")
+
+	config.Model = llm.DefaultConfig()
+	config.Limits = a2a.DefaultLoopLimits
+	config.ContextLimits = a2a.DefaultContextLimits
+	config.QueryPolicy = tools.DefaultQueryPolicy
+	config.Card = a2a.DefaultCardConfig
+	var overrides overrideFlags
+	overrides.register(config)
 	flag.Parse()
 
+	if config.ConfigFile != "" {
+		if err := loadConfigFile(config.ConfigFile, &config); err != nil {
+			log.Fatalf("Failed to load config: %v", err)
+		}
+	}
+	if err := applyEnvironment(&config); err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	overrides.apply(&config)
+
 	return config
 }
@@ -3,7 +3,11 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"fusion/internal/a2aclient"
+	"fusion/internal/citation"
+	"log"
 	"os"
 	"strings"
 	"time"
@@ -11,16 +15,24 @@ import (
 	"trpc.group/trpc-go/trpc-a2a-go/protocol"
 )
 
+// cancelCommand cancels a task instead of sending a message.
+const cancelCommand = "/cancel"
+
+// pushCommand sets the webhook that receives the push notifications of a task, or shows it with the
+// state of the notifications sent so far.
+const pushCommand = "/push"
+
 func main() {
-	a2aClient, err := client.NewA2AClient("http://localhost:8080", client.WithTimeout(300*time.Second))
+	// The agent accepts requests with a bearer token it knows, given in the A2A_TOKEN environment variable.
+	a2aClient, err := client.NewA2AClient("http://localhost:8080", client.WithHTTPClient(a2aclient.NewBearerClient(os.Getenv("A2A_TOKEN"))), client.WithTimeout(300*time.Second))
 	if err != nil {
-		fmt.Errorf("failed to create A2A client: %v", err)
+		log.Fatalf("Failed to create A2A client: %v", err)
 	}
 
 	contextID := protocol.GenerateContextID()
 	reader := bufio.NewReader(os.Stdin)
 
-	fmt.Println("Enter text to send to the agent.")
+	fmt.Println("Enter text to send to the agent, /cancel <task id> to cancel a task, or /push <task id> [webhook URL] [token] to set or show its push notifications.")
 	fmt.Println(strings.Repeat("-", 60))
 
 	for {
@@ -37,6 +49,16 @@ func main() {
 			continue
 		}
 
+		if strings.HasPrefix(input, cancelCommand) {
+			cancelTask(a2aClient, strings.TrimSpace(strings.TrimPrefix(input, cancelCommand)))
+			continue
+		}
+
+		if strings.HasPrefix(input, pushCommand) {
+			pushNotifications(a2aClient, strings.Fields(strings.TrimPrefix(input, pushCommand)))
+			continue
+		}
+
 		params := createMessageParams(input, contextID, 0)
 
 		handleStandardInteraction(a2aClient, params)
@@ -45,6 +67,57 @@ func main() {
 
 }
 
+func cancelTask(a2aClient *client.A2AClient, taskID string) {
+	if taskID == "" {
+		fmt.Println("usage: /cancel <task id>")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	task, err := a2aClient.CancelTasks(ctx, protocol.TaskIDParams{ID: taskID})
+	if err != nil {
+		fmt.Printf("failed to cancel task %s: %v\n", taskID, err)
+		return
+	}
+	fmt.Printf("[Task %s State %s]\n", task.ID, task.Status.State)
+}
+
+func pushNotifications(a2aClient *client.A2AClient, args []string) {
+	if len(args) == 0 || len(args) > 3 {
+		fmt.Println("usage: /push <task id> [webhook URL] [token]")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	taskID := args[0]
+	if len(args) == 1 {
+		config, err := a2aClient.GetPushNotification(ctx, protocol.TaskIDParams{ID: taskID})
+		if err != nil {
+			fmt.Printf("failed to get push notifications of task %s: %v\n", taskID, err)
+			return
+		}
+		fmt.Printf("[Task %s Webhook %s]\n", config.TaskID, config.PushNotificationConfig.URL)
+		deliveries, _ := json.MarshalIndent(config.Metadata["deliveries"], "", "  ")
+		fmt.Printf("Deliveries: %s\n", deliveries)
+		return
+	}
+
+	pushConfig := protocol.PushNotificationConfig{URL: args[1]}
+	if len(args) == 3 {
+		pushConfig.Token = args[2]
+	}
+	config, err := a2aClient.SetPushNotification(ctx, protocol.TaskPushNotificationConfig{TaskID: taskID, PushNotificationConfig: pushConfig})
+	if err != nil {
+		fmt.Printf("failed to set push notifications of task %s: %v\n", taskID, err)
+		return
+	}
+	fmt.Printf("[Task %s Webhook %s]\n", config.TaskID, config.PushNotificationConfig.URL)
+}
+
 func createMessageParams(input string, contextID string, historyLength int) protocol.SendMessageParams {
 	message := protocol.NewMessageWithContext(
 		protocol.MessageRoleUser,
@@ -53,14 +126,17 @@ func createMessageParams(input string, contextID string, historyLength int) prot
 		&contextID,
 	)
 
+	// The client waits for the answer, so the agent only responds once the task has ended.
+	blocking := true
 	params := protocol.SendMessageParams{
 		Message: message,
+		Configuration: &protocol.SendMessageConfiguration{
+			Blocking: &blocking,
+		},
 	}
 
 	if historyLength > 0 {
-		params.Configuration = &protocol.SendMessageConfiguration{
-			HistoryLength: &historyLength,
-		}
+		params.Configuration.HistoryLength = &historyLength
 	}
 
 	return params
@@ -138,6 +214,19 @@ func printTaskResult(task *protocol.Task) {
 					fmt.Printf("    Part %d (unknown): %+v", j+1, part)
 				}
 			}
+			printCitations(artifact.Parts)
 		}
 	}
 }
+
+func printCitations(parts []protocol.Part) {
+	citations := citation.FromParts(parts)
+	if len(citations) == 0 {
+		return
+	}
+
+	fmt.Println("Sources:")
+	for _, citation := range citations {
+		fmt.Printf("  [%d] %s (%s)\n", citation.Number, citation.Title, citation.ID)
+	}
+}
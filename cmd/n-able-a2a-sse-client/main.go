@@ -4,23 +4,31 @@ import (
 	"bufio"
 	"context"
 	"fmt"
+	"fusion/internal/a2aclient"
+	"fusion/internal/citation"
+	"log"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 	"trpc.group/trpc-go/trpc-a2a-go/client"
 	"trpc.group/trpc-go/trpc-a2a-go/protocol"
 )
 
+// cancelCommand cancels a task instead of sending a message.
+const cancelCommand = "/cancel"
+
 func main() {
-	a2aClient, err := client.NewA2AClient("http://localhost:8080", client.WithTimeout(300*time.Second))
+	// The agent accepts requests with a bearer token it knows, given in the A2A_TOKEN environment variable.
+	a2aClient, err := client.NewA2AClient("http://localhost:8080", client.WithHTTPClient(a2aclient.NewBearerClient(os.Getenv("A2A_TOKEN"))), client.WithTimeout(300*time.Second))
 	if err != nil {
-		fmt.Errorf("failed to create A2A client: %v", err)
+		log.Fatalf("Failed to create A2A client: %v", err)
 	}
 
 	contextID := protocol.GenerateContextID()
 	reader := bufio.NewReader(os.Stdin)
 
-	fmt.Println("Enter text to send to the agent.")
+	fmt.Println("Enter text to send to the agent, or /cancel [task id] to cancel a task. Ctrl+C cancels the task being streamed.")
 	fmt.Println(strings.Repeat("-", 60))
 
 	var currentTaskID *string
@@ -39,6 +47,16 @@ func main() {
 			continue
 		}
 
+		if strings.HasPrefix(input, cancelCommand) {
+			taskID := strings.TrimSpace(strings.TrimPrefix(input, cancelCommand))
+			if taskID == "" && currentTaskID != nil {
+				taskID = *currentTaskID
+			}
+			cancelTask(a2aClient, taskID)
+			currentTaskID = nil
+			continue
+		}
+
 		params := createMessageParams(input, contextID, currentTaskID, 0)
 
 		currentTaskID = handleStreamingInteraction(a2aClient, params)
@@ -47,6 +65,23 @@ func main() {
 
 }
 
+func cancelTask(a2aClient *client.A2AClient, taskID string) {
+	if taskID == "" {
+		fmt.Println("usage: /cancel <task id>")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	task, err := a2aClient.CancelTasks(ctx, protocol.TaskIDParams{ID: taskID})
+	if err != nil {
+		fmt.Printf("failed to cancel task %s: %v\n", taskID, err)
+		return
+	}
+	fmt.Printf("[Task %s State %s]\n", task.ID, task.Status.State)
+}
+
 func createMessageParams(input string, contextID string, taskID *string, historyLength int) protocol.SendMessageParams {
 	message := protocol.NewMessageWithContext(
 		protocol.MessageRoleUser,
@@ -84,7 +119,12 @@ func handleStreamingInteraction(a2aClient *client.A2AClient, params protocol.Sen
 		return nil
 	}
 
-	taskID := processStreamResponse(ctx, eventChan)
+	// Ctrl+C cancels the task while its response is streamed.
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	taskID := processStreamResponse(ctx, a2aClient, eventChan, interrupts)
 
 	fmt.Printf("Stream processing finished for message %s", params.Message.MessageID)
 	fmt.Println(strings.Repeat("-", 60))
@@ -92,7 +132,7 @@ func handleStreamingInteraction(a2aClient *client.A2AClient, params protocol.Sen
 	return taskID
 }
 
-func processStreamResponse(ctx context.Context, eventChan <-chan protocol.StreamingMessageEvent) *string {
+func processStreamResponse(ctx context.Context, a2aClient *client.A2AClient, eventChan <-chan protocol.StreamingMessageEvent, interrupts <-chan os.Signal) *string {
 	fmt.Println("\nAgent Response Stream:")
 	fmt.Println(strings.Repeat("-", 60))
 
@@ -105,6 +145,14 @@ func processStreamResponse(ctx context.Context, eventChan <-chan protocol.Stream
 			fmt.Printf("Context timeout or cancellation while waiting for stream events: %s", ctx.Err())
 			return nil
 
+		case <-interrupts:
+			if taskID == "" {
+				fmt.Println("No task to cancel yet")
+				continue
+			}
+			fmt.Println()
+			cancelTask(a2aClient, taskID)
+
 		case event, ok := <-eventChan:
 			if !ok {
 				fmt.Println("Stream channel closed")
@@ -149,17 +197,24 @@ func processStreamResponse(ctx context.Context, eventChan <-chan protocol.Stream
 				}
 			case *protocol.TaskArtifactUpdateEvent:
 				taskID = e.TaskID
-				name := getArtifactName(e.Artifact)
-				fmt.Printf("[Artifact Update: Task %s, Name %s]\n", e.TaskID, name)
+				appended := e.Append != nil && *e.Append
+				lastChunk := e.LastChunk != nil && *e.LastChunk
 
-				printParts(e.Artifact.Parts)
+				// Chunks of a streamed artifact are printed as they arrive so the answer appears token by token.
+				if !appended {
+					name := getArtifactName(e.Artifact)
+					fmt.Printf("[Artifact Update: Task %s, Name %s]\n", e.TaskID, name)
+				}
+				printChunk(e.Artifact.Parts)
 
-				if e.LastChunk != nil && *e.LastChunk {
-					fmt.Printf("Final artefact received with ID %s", e.Artifact.ArtifactID)
+				if lastChunk {
+					fmt.Println()
+					printCitations(e.Artifact.Parts)
+					fmt.Printf("Final artefact received with ID %s\n", e.Artifact.ArtifactID)
 				}
 
 			default:
-				fmt.Println("Warning: received unknown event type: %T\n ", event)
+				fmt.Printf("Warning: received unknown event type: %T\n", event.Result)
 			}
 		}
 
@@ -173,6 +228,14 @@ func getArtifactName(artifact protocol.Artifact) string {
 	return fmt.Sprintf("Artifact %s", artifact.ArtifactID)
 }
 
+func printChunk(parts []protocol.Part) {
+	for _, part := range parts {
+		if textPart, ok := part.(*protocol.TextPart); ok {
+			fmt.Print(textPart.Text)
+		}
+	}
+}
+
 func printMessage(message protocol.Message) {
 	printParts(message.Parts)
 }
@@ -195,3 +258,15 @@ func printPart(part interface{}) {
 		fmt.Printf("%sUnsupported part type: %T\n", indent, p)
 	}
 }
+
+func printCitations(parts []protocol.Part) {
+	citations := citation.FromParts(parts)
+	if len(citations) == 0 {
+		return
+	}
+
+	fmt.Println("Sources:")
+	for _, citation := range citations {
+		fmt.Printf("  [%d] %s (%s)\n", citation.Number, citation.Title, citation.ID)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"fusion/internal/knowledge"
+	"log"
+)
+
+type Config struct {
+	Articles string
+	Index    string
+	Query    string
+	Limit    int
+}
+
+func main() {
+
+	config := parseFlags()
+
+	if config.Query != "" {
+		index, err := knowledge.LoadIndex(config.Index)
+		if err != nil {
+			log.Fatalf("Failed to load index: %v", err)
+		}
+
+		results := index.Search(config.Query, config.Limit)
+		if len(results) == 0 {
+			fmt.Println("No articles matched")
+			return
+		}
+		for _, result := range results {
+			fmt.Printf("%.3f  %s  %s\n       %s\n", result.Score, result.ID, result.Title, result.Snippet)
+		}
+		return
+	}
+
+	index, err := knowledge.BuildIndexFromDir(config.Articles)
+	if err != nil {
+		log.Fatalf("Failed to build index: %v", err)
+	}
+
+	if err := index.Save(config.Index); err != nil {
+		log.Fatalf("Failed to save index: %v", err)
+	}
+
+	fmt.Printf("Indexed %d articles and %d terms into %s\n", len(index.Articles), len(index.Postings), config.Index)
+}
+
+func parseFlags() Config {
+	var config Config
+
+	flag.StringVar(&config.Articles, "articles", "knowledge/articles", "Directory of Markdown and JSON knowledge articles")
+	flag.StringVar(&config.Index, "index", "knowledge/index.json", "Path the index is written to")
+	flag.StringVar(&config.Query, "query", "", "Search the existing index instead of rebuilding it")
+	flag.IntVar(&config.Limit, "limit", 3, "Number of results shown for -query")
+	flag.Parse()
+
+	return config
+}
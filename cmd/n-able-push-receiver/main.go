@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"trpc.group/trpc-go/trpc-a2a-go/auth"
+	"trpc.group/trpc-go/trpc-a2a-go/protocol"
+)
+
+// The receiver is a webhook for testing push notifications locally. It checks the signature of every
+// notification against the JWKS of the agent and prints the events it receives. The agent must be
+// run with -push-private-webhooks to send notifications to it on localhost.
+
+type Config struct {
+	Address string
+	JWKSURL string
+	Token   string
+	// FailFirst is the number of notifications answered with an error, to try the agent's retries.
+	FailFirst int
+}
+
+type notification struct {
+	Kind      string               `json:"kind"`
+	TaskID    string               `json:"taskId"`
+	ContextID string               `json:"contextId"`
+	Final     bool                 `json:"final"`
+	Status    *protocol.TaskStatus `json:"status"`
+	Artifact  *protocol.Artifact   `json:"artifact"`
+	LastChunk *bool                `json:"lastChunk"`
+}
+
+func main() {
+
+	config := parseFlags()
+
+	verifier := auth.NewPushNotificationAuthenticator()
+	verifier.SetJWKSClient(config.JWKSURL)
+
+	var mu sync.Mutex
+	received := 0
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		deliveryID := r.Header.Get("X-A2A-Notification-ID")
+		if err := verifier.VerifyPushNotification(r, body); err != nil {
+			fmt.Printf("[Rejected %s] invalid signature: %v\n", deliveryID, err)
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		if config.Token != "" && r.Header.Get("X-A2A-Notification-Token") != config.Token {
+			fmt.Printf("[Rejected %s] unexpected notification token\n", deliveryID)
+			http.Error(w, "invalid notification token", http.StatusUnauthorized)
+			return
+		}
+
+		mu.Lock()
+		received++
+		fail := received <= config.FailFirst
+		mu.Unlock()
+		if fail {
+			fmt.Printf("[Failed %s] answering with an error to test retries\n", deliveryID)
+			http.Error(w, "failing on purpose", http.StatusServiceUnavailable)
+			return
+		}
+
+		var event notification
+		if err := json.Unmarshal(body, &event); err != nil {
+			fmt.Printf("[Rejected %s] invalid event: %v\n", deliveryID, err)
+			http.Error(w, "invalid event", http.StatusBadRequest)
+			return
+		}
+		printNotification(deliveryID, event)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	fmt.Printf("Receiving push notifications on %s, verified with %s\n", config.Address, config.JWKSURL)
+	if err := http.ListenAndServe(config.Address, nil); err != nil {
+		log.Fatalf("Receiver failed: %v", err)
+	}
+}
+
+func printNotification(deliveryID string, event notification) {
+	switch event.Kind {
+	case protocol.KindTaskStatusUpdate:
+		state := protocol.TaskState("")
+		if event.Status != nil {
+			state = event.Status.State
+		}
+		fmt.Printf("[%s] Task %s State %s Final %t\n", deliveryID, event.TaskID, state, event.Final)
+	case protocol.KindTaskArtifactUpdate:
+		if event.Artifact == nil {
+			fmt.Printf("[%s] Task %s artifact update without an artifact\n", deliveryID, event.TaskID)
+			return
+		}
+		name := "Unnamed"
+		if event.Artifact.Name != nil {
+			name = *event.Artifact.Name
+		}
+		lastChunk := event.LastChunk != nil && *event.LastChunk
+		fmt.Printf("[%s] Task %s Artifact %s (%s) Last chunk %t\n", deliveryID, event.TaskID, name, event.Artifact.ArtifactID, lastChunk)
+		for _, part := range event.Artifact.Parts {
+			if textPart, ok := part.(*protocol.TextPart); ok {
+				fmt.Println(textPart.Text)
+			}
+		}
+	default:
+		fmt.Printf("[%s] Task %s unknown event %s\n", deliveryID, event.TaskID, event.Kind)
+	}
+}
+
+func parseFlags() Config {
+	var config Config
+
+	flag.StringVar(&config.Address, "address", "localhost:9090", "Address to receive push notifications on")
+	flag.StringVar(&config.JWKSURL, "jwks", "http://localhost:8080"+protocol.JWKSPath, "JWKS URL of the agent that signs the notifications")
+	flag.StringVar(&config.Token, "token", "", "Notification token expected with every notification. It is not checked when empty")
+	flag.IntVar(&config.FailFirst, "fail-first", 0, "Number of notifications answered with an error before any are accepted")
+	flag.Parse()
+
+	return config
+}
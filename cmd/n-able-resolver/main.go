@@ -1,24 +1,29 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"fusion/graph"
+	"fusion/internal/a2aclient"
 	"fusion/internal/resolver"
 	"github.com/99designs/gqlgen/graphql/handler"
 	"github.com/99designs/gqlgen/graphql/handler/extension"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/gorilla/websocket"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 	"trpc.group/trpc-go/trpc-a2a-go/client"
 )
 
 func main() {
 
-	a2aClient, err := client.NewA2AClient("http://localhost:8080", client.WithTimeout(300*time.Second))
+	// Requests to the agent carry the token of the GraphQL caller they are made for.
+	a2aClient, err := client.NewA2AClient("http://localhost:8080", client.WithHTTPClient(a2aclient.NewBearerClient("")), client.WithTimeout(300*time.Second))
 	if err != nil {
-		fmt.Errorf("failed to create A2A client: %v", err)
+		log.Fatalf("Failed to create A2A client: %v", err)
 	}
 
 	agentResolver, err := resolver.NewResolver(a2aClient)
@@ -30,6 +35,9 @@ func main() {
 
 	srv.AddTransport(transport.Websocket{
 		KeepAlivePingInterval: 300 * time.Second,
+		InitFunc: func(ctx context.Context, initPayload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+			return withCallerToken(ctx, initPayload.Authorization()), &initPayload, nil
+		},
 		Upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true
@@ -42,8 +50,19 @@ func main() {
 	srv.Use(extension.Introspection{})
 
 	http.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	http.Handle("/query", srv)
+	http.Handle("/query", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srv.ServeHTTP(w, r.WithContext(withCallerToken(r.Context(), r.Header.Get("Authorization"))))
+	}))
 
 	fmt.Printf("connect to http://localhost:8180/ for GraphQL playground")
 	http.ListenAndServe(":8180", nil)
 }
+
+// withCallerToken passes the bearer token of a GraphQL caller on to the agent, which checks it.
+func withCallerToken(ctx context.Context, authorization string) context.Context {
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok || token == "" {
+		return ctx
+	}
+	return a2aclient.WithBearerToken(ctx, token)
+}
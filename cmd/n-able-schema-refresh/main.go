@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"fusion/internal/tools"
+	"log"
+	"os"
+	"time"
+)
+
+type Config struct {
+	Token    string
+	Endpoint string
+	Output   string
+	DryRun   bool
+}
+
+func main() {
+
+	config := parseFlags()
+
+	client := tools.NewGraphQLClient(config.Endpoint, tools.WithQueryTimeout(30*time.Second))
+
+	live, err := tools.IntrospectSchema(context.Background(), client, config.Token)
+	if err != nil {
+		log.Fatalf("Failed to introspect schema: %v", err)
+	}
+
+	liveSchema, err := tools.ParseSchema(live)
+	if err != nil {
+		log.Fatalf("Introspected schema is invalid: %v", err)
+	}
+
+	embeddedSchema, err := tools.ParseSchema([]byte(tools.AssetsSchema))
+	if err != nil {
+		log.Fatalf("Embedded schema is invalid: %v", err)
+	}
+
+	changes := tools.DiffSchemas(embeddedSchema, liveSchema)
+	if len(changes) == 0 {
+		fmt.Println("Embedded schema is up to date")
+		return
+	}
+
+	fmt.Printf("%d changes between the embedded and live schema:\n", len(changes))
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+
+	if config.DryRun {
+		return
+	}
+
+	var snapshot bytes.Buffer
+	if err := json.Indent(&snapshot, live, "", "    "); err != nil {
+		log.Fatalf("Failed to format schema: %v", err)
+	}
+	snapshot.WriteString("\n")
+
+	if err := os.WriteFile(config.Output, snapshot.Bytes(), 0o644); err != nil {
+		log.Fatalf("Failed to write schema snapshot: %v", err)
+	}
+
+	fmt.Printf("Embedded schema snapshot written to %s\n", config.Output)
+}
+
+func parseFlags() Config {
+	var config Config
+
+	flag.StringVar(&config.Token, "token", "", "User SSO Token")
+	flag.StringVar(&config.Endpoint, "endpoint", "staging", "GraphQL endpoint: prod, staging or the URL of another server")
+	flag.StringVar(&config.Output, "output", "internal/tools/assets_schema.json", "Path of the embedded schema snapshot")
+	flag.BoolVar(&config.DryRun, "dry-run", false, "Show the differences without writing the snapshot")
+	flag.Parse()
+
+	return config
+}
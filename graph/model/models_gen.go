@@ -15,10 +15,17 @@ type AgentResponse struct {
 }
 
 type Artifact struct {
-	ArtifactID  string  `json:"artifactId"`
-	Name        *string `json:"name,omitempty"`
-	Description *string `json:"description,omitempty"`
-	Parts       []Part  `json:"parts"`
+	ArtifactID  string      `json:"artifactId"`
+	Name        *string     `json:"name,omitempty"`
+	Description *string     `json:"description,omitempty"`
+	Parts       []Part      `json:"parts"`
+	Citations   []*Citation `json:"citations"`
+}
+
+type Citation struct {
+	Number int32  `json:"number"`
+	ID     string `json:"id"`
+	Title  string `json:"title"`
 }
 
 type FilePart struct {
@@ -45,6 +52,9 @@ type MessageInput struct {
 	Text      string  `json:"text"`
 }
 
+type Mutation struct {
+}
+
 type Query struct {
 }
 
@@ -55,6 +65,8 @@ type TaskArtifactUpdate struct {
 	TaskID    *string   `json:"taskId,omitempty"`
 	ContextID *string   `json:"contextId,omitempty"`
 	Artifact  *Artifact `json:"artifact,omitempty"`
+	Append    *bool     `json:"append,omitempty"`
+	LastChunk *bool     `json:"lastChunk,omitempty"`
 }
 
 func (TaskArtifactUpdate) IsProcessingResult() {}
@@ -10,6 +10,11 @@ import (
 	"fusion/graph/model"
 )
 
+// CancelTask is the resolver for the cancelTask field.
+func (r *mutationResolver) CancelTask(ctx context.Context, taskID string) (*model.TaskStatusUpdate, error) {
+	panic(fmt.Errorf("not implemented: CancelTask - cancelTask"))
+}
+
 // Placeholder is the resolver for the placeholder field.
 func (r *queryResolver) Placeholder(ctx context.Context) (*string, error) {
 	panic(fmt.Errorf("not implemented: Placeholder - placeholder"))
@@ -20,11 +25,15 @@ func (r *subscriptionResolver) AgentSendMessage(ctx context.Context, message *mo
 	panic(fmt.Errorf("not implemented: AgentSendMessage - agentSendMessage"))
 }
 
+// Mutation returns MutationResolver implementation.
+func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+
 // Query returns QueryResolver implementation.
 func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
 
 // Subscription returns SubscriptionResolver implementation.
 func (r *Resolver) Subscription() SubscriptionResolver { return &subscriptionResolver{r} }
 
+type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
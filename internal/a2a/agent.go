@@ -2,10 +2,13 @@ package a2a
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"fusion/internal/citation"
+	"fusion/internal/knowledge"
+	"fusion/internal/llm"
 	"fusion/internal/tools"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"strings"
 	"time"
 	"trpc.group/trpc-go/trpc-a2a-go/protocol"
 	"trpc.group/trpc-go/trpc-a2a-go/taskmanager"
@@ -25,23 +28,55 @@ const systemPrompt = `
 	"You are an IT Technician, capable of providing detailed answers to the questions that your customers ask regarding their assets." +
 	"Think before you reply. Inform the customer of each step you are going to take. This includes the use of any tools. Always provide the results from using a tool to the user." +
 	"Determine if there are any knowledge articles related to the question that could help with your reply." +
+	"When your reply uses a knowledge article, cite it with the article's citation marker, for example [1]." +
 	"Use available tools to collect data from assets"
 `
 
+const continuePrompt = "Your previous response was cut off by the output token limit. Continue exactly where it stopped, without repeating any of it."
+
+// modelMetadataKey is the message metadata key a client uses to pick one of the configured models.
+const modelMetadataKey = "model"
+
+// organizationsMetadataKey is the message metadata key a client uses to search only some of its
+// organizations.
+const organizationsMetadataKey = "organizations"
+
 type assetManagementAgent struct {
-	ModelClient *modelClient
-	Token       string
+	Model       llm.ChatModel
+	ModelConfig llm.Config
+	Registry    *tools.Registry
+	Limits      LoopLimits
+	// ExposeReasoning sends the model's reasoning to the client as a separate artifact.
+	ExposeReasoning bool
+	// Transcripts keeps the conversation of each context between tasks. It is not kept when nil.
+	Transcripts   TranscriptStore
+	ContextLimits ContextLimits
+	// Results keeps the full text of truncated tool results. Without it the rest cannot be read.
+	Results tools.ResultStore
+	// Push sends the events of a task to the webhook set for it. No notifications are sent when nil.
+	Push *PushNotifier
+	// Owners records the caller who created each task. Owners are not recorded when nil.
+	Owners *TaskOwners
+	// UnscopedCallers lets callers without an organizations claim see the assets of every
+	// organization. Such callers are denied when it is false.
+	UnscopedCallers bool
+
+	running runningTasks
 }
 
-func NewAgent(token string) (*assetManagementAgent, error) {
-	modelClient, err := NewModelClient()
-	if err != nil {
-		return nil, err
-	}
+func NewAgent(model llm.ChatModel, modelConfig llm.Config, limits LoopLimits, exposeReasoning bool, transcripts TranscriptStore, contextLimits ContextLimits, results tools.ResultStore, client *tools.GraphQLClient, schemas *tools.SchemaProvider, pagination tools.PaginationLimits, policy *tools.QueryPolicy, knowledgeIndex *knowledge.Index) (*assetManagementAgent, error) {
+	registry := tools.NewDefaultRegistry(client, schemas, pagination, policy, knowledgeIndex, results, contextLimits.ResultChunkSize())
+	registry.MaxParallel = limits.MaxParallelTools
 
 	return &assetManagementAgent{
-		ModelClient: modelClient,
-		Token:       token,
+		Model:           model,
+		ModelConfig:     modelConfig,
+		Registry:        registry,
+		Limits:          limits,
+		ExposeReasoning: exposeReasoning,
+		Transcripts:     transcripts,
+		ContextLimits:   contextLimits,
+		Results:         results,
 	}, nil
 }
 
@@ -60,37 +95,160 @@ func (p *assetManagementAgent) ProcessMessage(ctx context.Context, message proto
 		}, nil
 	}
 
+	// The tools call the GraphQL API with the caller's own token and only search the caller's
+	// organizations, so that callers only see their own assets.
+	identity, err := callerFromContext(ctx, p.UnscopedCallers)
+	if err == nil {
+		identity.Organizations, err = requestedOrganizations(message, identity.Organizations)
+	}
+	if err != nil {
+		fmt.Printf("process message - %v\n", err)
+		errMsg := protocol.NewMessage(
+			protocol.MessageRoleAgent,
+			[]protocol.Part{protocol.NewTextPart(err.Error())},
+		)
+
+		return &taskmanager.MessageProcessingResult{
+			Result: &errMsg,
+		}, nil
+	}
+
+	modelID, err := p.requestedModel(message)
+	if err != nil {
+		fmt.Printf("process message - %v\n", err)
+		errMsg := protocol.NewMessage(
+			protocol.MessageRoleAgent,
+			[]protocol.Part{protocol.NewTextPart(err.Error())},
+		)
+
+		return &taskmanager.MessageProcessingResult{
+			Result: &errMsg,
+		}, nil
+	}
+
 	specificTaskID := message.TaskID
 	taskID, err := handle.BuildTask(specificTaskID, message.ContextID)
 	if err != nil {
 		return nil, fmt.Errorf("process message - failed to create task: %w", err)
 	}
 
+	if p.Owners != nil {
+		if err := p.Owners.Claim(ctx, taskID, identity); err != nil {
+			return nil, fmt.Errorf("process message - %w", err)
+		}
+	}
+
+	if p.Push != nil {
+		if options.PushNotificationConfig != nil {
+			if err := p.Push.SetConfig(ctx, taskID, *options.PushNotificationConfig); err != nil {
+				return nil, fmt.Errorf("process message - failed to set push notification config: %w", err)
+			}
+		}
+		task, err := handle.GetTask(&taskID)
+		if err != nil {
+			return nil, fmt.Errorf("process message - failed to get task: %w", err)
+		}
+		handle = p.Push.handle(handle, task.Task().ContextID)
+	}
+
 	if options.Streaming {
-		return p.processStreamingMode(ctx, inputText, message.ContextID, taskID, handle)
+		return p.processStreamingMode(ctx, inputText, modelID, identity, message.ContextID, taskID, handle)
 	}
 
-	return p.processNonStreamingMode(ctx, inputText, message.ContextID, taskID, handle)
+	if detached(options.Blocking, options.PushNotificationConfig) {
+		return p.processDetachedMode(ctx, inputText, modelID, identity, message.ContextID, taskID, handle)
+	}
+
+	return p.processNonStreamingMode(ctx, inputText, modelID, identity, message.ContextID, taskID, handle)
 
 }
 
-func (p *assetManagementAgent) processStreamingMode(ctx context.Context, inputText string, contextID *string, taskID string, handle taskmanager.TaskHandler) (*taskmanager.MessageProcessingResult, error) {
+// detached reports whether a message/send request is answered before its task is processed. The
+// client then reads the task later or is called back, so the task must outlive the request.
+func detached(blocking bool, push *protocol.PushNotificationConfig) bool {
+	return !blocking || push != nil
+}
+
+// requestedOrganizations narrows the caller's organizations to those picked in the message metadata.
+// Organizations outside the caller's own cannot be picked.
+func requestedOrganizations(message protocol.Message, allowed []string) ([]string, error) {
+	value, ok := message.Metadata[organizationsMetadataKey]
+	if !ok {
+		return allowed, nil
+	}
+
+	requested, err := organizationsValue(value, false)
+	if err != nil {
+		return nil, fmt.Errorf("metadata %s is invalid: %w", organizationsMetadataKey, err)
+	}
+	if allowed != nil {
+		for _, organization := range requested {
+			if !tools.Contains(allowed, organization) {
+				return nil, fmt.Errorf("metadata %s names organization %s, which is not one of yours", organizationsMetadataKey, organization)
+			}
+		}
+	}
+	return requested, nil
+}
+
+// requestedModel returns the model picked in the message metadata, or an empty string for the default model.
+func (p *assetManagementAgent) requestedModel(message protocol.Message) (string, error) {
+	value, ok := message.Metadata[modelMetadataKey]
+	if !ok {
+		return "", nil
+	}
+	name, ok := value.(string)
+	if !ok || name == "" {
+		return "", fmt.Errorf("metadata %s must be the name of a model", modelMetadataKey)
+	}
+	return p.ModelConfig.ResolveModel(name)
+}
+
+func (p *assetManagementAgent) processStreamingMode(ctx context.Context, inputText string, modelID string, identity caller, contextID *string, taskID string, handle taskmanager.TaskHandler) (*taskmanager.MessageProcessingResult, error) {
 
 	subscriber, err := handle.SubScribeTask(&taskID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to subscribe to task: %w", err)
 	}
 
-	go p.processRequest(ctx, inputText, contextID, taskID, handle)
+	// The task is registered before the goroutine starts, so that it can be canceled straight away.
+	taskCtx, finish := p.running.start(ctx, taskID)
+	go func() {
+		defer finish()
+		p.processRequest(taskCtx, inputText, modelID, identity, contextID, taskID, handle, true)
+	}()
 
 	return &taskmanager.MessageProcessingResult{
 		StreamingEvents: subscriber,
 	}, nil
 }
 
-func (p *assetManagementAgent) processNonStreamingMode(ctx context.Context, inputText string, contextID *string, taskID string, handle taskmanager.TaskHandler) (*taskmanager.MessageProcessingResult, error) {
+// processDetachedMode returns the submitted task straight away and processes it after the client has
+// hung up. The task can still be canceled.
+func (p *assetManagementAgent) processDetachedMode(ctx context.Context, inputText string, modelID string, identity caller, contextID *string, taskID string, handle taskmanager.TaskHandler) (*taskmanager.MessageProcessingResult, error) {
 
-	p.processRequest(ctx, inputText, contextID, taskID, handle)
+	submitted, err := handle.GetTask(&taskID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	// The task is registered before the goroutine starts, so that it can be canceled straight away.
+	taskCtx, finish := p.running.start(context.WithoutCancel(ctx), taskID)
+	go func() {
+		defer finish()
+		p.processRequest(taskCtx, inputText, modelID, identity, contextID, taskID, handle, false)
+	}()
+
+	return &taskmanager.MessageProcessingResult{
+		Result: submitted.Task(),
+	}, nil
+}
+
+func (p *assetManagementAgent) processNonStreamingMode(ctx context.Context, inputText string, modelID string, identity caller, contextID *string, taskID string, handle taskmanager.TaskHandler) (*taskmanager.MessageProcessingResult, error) {
+
+	taskCtx, finish := p.running.start(ctx, taskID)
+	p.processRequest(taskCtx, inputText, modelID, identity, contextID, taskID, handle, false)
+	finish()
 
 	cancellable, err := handle.GetTask(&taskID)
 	if err != nil {
@@ -102,123 +260,337 @@ func (p *assetManagementAgent) processNonStreamingMode(ctx context.Context, inpu
 	}, nil
 }
 
-func (p *assetManagementAgent) processRequest(ctx context.Context, inputText string, contextID *string, taskID string, handle taskmanager.TaskHandler) {
-	var temperature float32 = 0.0
-
-	messages := handle.GetMessageHistory()
-	converseMessages := mapMessagesToConverseMessages(messages)
-
-	toolConfig := tools.ToolConfig(handle, taskID, contextID, p.Token)
-	converseInput := &bedrockruntime.ConverseInput{
-		System: []types.SystemContentBlock{
-			&types.SystemContentBlockMemberText{
-				Value: systemPrompt,
-			},
-		},
-		ModelId:    &p.ModelClient.BedrockModel,
-		Messages:   converseMessages,
-		ToolConfig: &toolConfig,
-		InferenceConfig: &types.InferenceConfiguration{
-			Temperature: &temperature,
-		},
+// processRequest runs the conversation with the model until it produces an answer. In streaming mode
+// the text of every model turn is sent to the client as it is generated.
+func (p *assetManagementAgent) processRequest(ctx context.Context, inputText string, modelID string, identity caller, contextID *string, taskID string, handle taskmanager.TaskHandler, streaming bool) {
+	temperature := p.ModelConfig.Temperature
+
+	messages, citations := p.conversation(ctx, identity, contextID, inputText, handle)
+
+	invocation := &tools.Invocation{
+		Handle:        handle,
+		TaskID:        taskID,
+		ContextID:     contextID,
+		Token:         identity.Token,
+		CallerID:      identity.ID,
+		Organizations: identity.Organizations,
+		Citations:     citation.NewCitations(citations),
 	}
 
-	converseLoop := true
+	budget := newLoopBudget(p.Limits)
+	if p.Limits.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Limits.MaxDuration)
+		defer cancel()
+	}
+	toolCtx := tools.WithInvocation(ctx, invocation)
+
+	request := &llm.Request{
+		Model:       modelID,
+		System:      systemPrompt,
+		Messages:    messages,
+		Tools:       p.Registry.ToolSpecs(),
+		Temperature: &temperature,
+		MaxTokens:   p.ModelConfig.MaxTokens,
+	}
 
-	for converseLoop {
-		converseOutput, err := p.ModelClient.Converse(ctx, converseInput)
-		if err != nil {
-			err = handle.UpdateTaskState(&taskID, protocol.TaskStateFailed, nil)
-			if err != nil {
-				fmt.Errorf("failed to update task status to failed: %w", err)
+	contextManager := &contextManager{limits: p.ContextLimits, model: p.Model, results: p.Results, scope: identity.scope()}
+
+	// continued holds the text of turns cut short by the output token limit. The turns that follow
+	// complete it, and in streaming mode they are appended to the same artifact.
+	var continued strings.Builder
+	var stream *artifactStream
+	var reasoning []string
+
+	for {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			// A stream is still open only when the last turn is being continued.
+			var open *artifactStream
+			if continued.Len() > 0 {
+				open = stream
 			}
+			p.stopCanceled(handle, taskID, contextID, open, budget)
 			return
 		}
 
-		converseMessage, ok := converseOutput.Output.(*types.ConverseOutputMemberMessage)
-		if !ok {
+		if err := budget.check(); err != nil {
+			p.stopAtLimit(handle, taskID, contextID, budget, err)
+			return
+		}
+
+		budget.addUsage(contextManager.prepare(ctx, taskID, request))
+
+		var response *llm.Response
+		var err error
+		if streaming {
+			if continued.Len() == 0 {
+				stream = newArtifactStream(handle, taskID)
+			}
+			response, err = p.Model.ChatStream(ctx, request, stream.Write)
+		} else {
+			response, err = p.Model.Chat(ctx, request)
+		}
+		if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+			p.stopCanceled(handle, taskID, contextID, stream, budget)
+			return
+		}
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			if stream != nil && stream.Started() {
+				err = stream.Close(protocol.Artifact{
+					Name:        llm.StringPtr("Partial Response"),
+					Description: llm.StringPtr("Model output before the task ran out of time"),
+				})
+				if err != nil {
+					fmt.Printf("failed to send partial artifact: %v\n", err)
+				}
+			}
+			p.stopAtLimit(handle, taskID, contextID, budget, budget.timeout())
+			return
+		}
+		if err != nil {
 			err = handle.UpdateTaskState(&taskID, protocol.TaskStateFailed, nil)
 			if err != nil {
-				fmt.Errorf("failed to extract converse message: %w", err)
+				fmt.Printf("failed to update task status to failed: %v\n", err)
 			}
 			return
 		}
+		budget.record(response)
+
+		switch response.StopReason {
+		case llm.StopReasonEndTurn, llm.StopReasonStopSequence:
+			reasoning = append(reasoning, reasoningText(response.Message)...)
+			text := continued.String() + response.Message.Text()
+			if strings.TrimSpace(text) == "" {
+				p.endTask(handle, taskID, contextID, protocol.TaskStateFailed,
+					"The model finished without writing an answer.", budget.metadata())
+				return
+			}
 
-		switch converseOutput.StopReason {
-		case types.StopReasonEndTurn:
-			content, ok := converseMessage.Value.Content[0].(*types.ContentBlockMemberText)
-			if !ok {
-				err = handle.UpdateTaskState(&taskID, protocol.TaskStateFailed, nil)
+			if p.ExposeReasoning && len(reasoning) > 0 {
+				err = handle.AddArtifact(&taskID, protocol.Artifact{
+					ArtifactID:  protocol.GenerateArtifactID(),
+					Name:        llm.StringPtr("Reasoning"),
+					Description: llm.StringPtr("The model's reasoning while answering"),
+					Parts:       []protocol.Part{protocol.NewTextPart(strings.Join(reasoning, "\n\n"))},
+				}, true, false)
 				if err != nil {
-					fmt.Errorf("failed to extract end turn converse message: %w", err)
+					fmt.Printf("failed to send reasoning artifact: %v\n", err)
 				}
-				return
 			}
 
+			metadata := budget.metadata()
+			metadata["processedAt"] = time.Now().UTC().Format(time.RFC3339)
 			artifact := protocol.Artifact{
 				ArtifactID:  protocol.GenerateArtifactID(),
-				Name:        stringPtr("Final Response"),
-				Description: stringPtr("Response from model"),
-				Parts:       []protocol.Part{protocol.NewTextPart(content.Value)},
-				Metadata: map[string]interface{}{
-					"processedAt": time.Now().UTC().Format(time.RFC3339),
-				},
+				Name:        llm.StringPtr("Final Response"),
+				Description: llm.StringPtr("Response from model"),
+				Parts:       responseParts(text, invocation.Citations),
+				Metadata:    metadata,
 			}
 
-			err = handle.AddArtifact(&taskID, artifact, true, false)
+			if stream != nil && stream.Started() {
+				err = stream.Close(artifact)
+			} else {
+				err = handle.AddArtifact(&taskID, artifact, true, false)
+			}
 			if err != nil {
-				fmt.Errorf("failed to send artifact event: %w", err)
+				fmt.Printf("failed to send artifact event: %v\n", err)
 				return
 			}
 
+			p.saveTranscript(ctx, identity, contextID, &Transcript{
+				Messages:  append(request.Messages, response.Message),
+				Citations: invocation.Citations.List(),
+			})
+
 			err = handle.UpdateTaskState(&taskID, protocol.TaskStateCompleted, nil)
 			if err != nil {
-				fmt.Errorf("failed to send completed event: %w", err)
-				return
+				fmt.Printf("failed to send completed event: %v\n", err)
 			}
+			return
 
-			converseLoop = false
-
-		case types.StopReasonToolUse:
-
-			for _, item := range converseMessage.Value.Content {
-				switch d := item.(type) {
-				case *types.ContentBlockMemberText:
+		case llm.StopReasonToolUse:
+			reasoning = append(reasoning, reasoningText(response.Message)...)
 
-					err = handle.UpdateTaskState(&taskID, protocol.TaskStateWorking, &protocol.Message{
-						ContextID: contextID,
-						MessageID: protocol.GenerateMessageID(),
-						Role:      protocol.MessageRoleAgent,
-						Parts:     []protocol.Part{protocol.NewTextPart(d.Value)},
-					})
-					if err != nil {
-						fmt.Errorf("failed to send progress event: %w", err)
-						return
+			if stream != nil && stream.Started() {
+				// The text of the turn has already been streamed, so it is not repeated as progress messages.
+				err = stream.Close(protocol.Artifact{
+					Name:        llm.StringPtr("Progress"),
+					Description: llm.StringPtr("Model commentary before using tools"),
+				})
+				if err != nil {
+					fmt.Printf("failed to send progress artifact: %v\n", err)
+					return
+				}
+			} else {
+				progress := continued.String()
+				for _, item := range response.Message.Content {
+					switch d := item.(type) {
+					case *llm.TextBlock:
+
+						err = handle.UpdateTaskState(&taskID, protocol.TaskStateWorking, &protocol.Message{
+							ContextID: contextID,
+							MessageID: protocol.GenerateMessageID(),
+							Role:      protocol.MessageRoleAgent,
+							Parts:     []protocol.Part{protocol.NewTextPart(progress + d.Text)},
+						})
+						if err != nil {
+							fmt.Printf("failed to send progress event: %v\n", err)
+							return
+						}
+						progress = ""
 					}
 				}
 			}
+			continued.Reset()
 
-			err := tools.HandleToolUse(converseOutput.Output, &converseInput.Messages, p.Token)
+			err := p.Registry.HandleToolUse(toolCtx, response.Message, &request.Messages)
 
+			if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+				p.stopCanceled(handle, taskID, contextID, nil, budget)
+				return
+			}
 			if err != nil {
 				err = handle.UpdateTaskState(&taskID, protocol.TaskStateFailed, nil)
 				if err != nil {
-					fmt.Errorf("failed to send failed event after tool use: %w", err)
+					fmt.Printf("failed to send failed event after tool use: %v\n", err)
 				}
 				return
 			}
-			continue
 
-		case types.StopReasonMaxTokens:
-		case types.StopReasonContentFiltered:
-		case types.StopReasonGuardrailIntervened:
+		case llm.StopReasonMaxTokens:
+			reasoning = append(reasoning, reasoningText(response.Message)...)
+			text := response.Message.Text()
+			if text == "" {
+				p.endTask(handle, taskID, contextID, protocol.TaskStateFailed,
+					"The model ran out of output tokens before it wrote any of its answer.", budget.metadata())
+				return
+			}
+
+			// The model is asked to carry on from where it stopped. Only the text of the turn is kept, as
+			// a tool use that was cut short has incomplete input.
+			fmt.Printf("task %s: model turn cut short by the output token limit, continuing\n", taskID)
+			continued.WriteString(text)
+			request.Messages = append(request.Messages,
+				llm.Message{Role: llm.RoleAssistant, Content: []llm.ContentBlock{&llm.TextBlock{Text: text}}},
+				llm.Message{Role: llm.RoleUser, Content: []llm.ContentBlock{&llm.TextBlock{Text: continuePrompt}}},
+			)
+
+		case llm.StopReasonContentFiltered, llm.StopReasonGuardrailIntervened:
+			if stream != nil && stream.Started() {
+				err = stream.Close(protocol.Artifact{
+					Name:        llm.StringPtr("Blocked Response"),
+					Description: llm.StringPtr("Model output before it was blocked"),
+				})
+				if err != nil {
+					fmt.Printf("failed to send blocked artifact: %v\n", err)
+				}
+			}
+
+			text := "I can't answer this request because the response was blocked by a content filter."
+			if response.StopReason == llm.StopReasonGuardrailIntervened {
+				text = "I can't answer this request because it was blocked by a guardrail."
+			}
+			p.endTask(handle, taskID, contextID, protocol.TaskStateRejected, text, budget.metadata())
+			return
+
 		default:
-			fmt.Errorf("unsupported stop reason")
+			fmt.Printf("unsupported stop reason %s\n", response.StopReason)
+			p.endTask(handle, taskID, contextID, protocol.TaskStateFailed,
+				fmt.Sprintf("The model stopped for an unsupported reason: %s.", response.StopReason), budget.metadata())
 			return
 		}
 	}
 }
 
+// conversation returns the messages the model continues from and the citations numbered earlier in
+// the context. The transcript of the context is used when there is one, as the A2A history only
+// holds the text of earlier messages and none of the tool uses and results.
+func (p *assetManagementAgent) conversation(ctx context.Context, identity caller, contextID *string, inputText string, handle taskmanager.TaskHandler) ([]llm.Message, []citation.Citation) {
+	if p.Transcripts != nil && contextID != nil && *contextID != "" {
+		transcript, ok, err := p.Transcripts.Load(ctx, transcriptKey(identity, *contextID))
+		if err != nil {
+			fmt.Printf("failed to load transcript of context %s: %v\n", *contextID, err)
+		} else if ok && len(transcript.Messages) > 0 {
+			messages := transcript.Messages
+			input := &llm.TextBlock{Text: inputText}
+			if last := &messages[len(messages)-1]; last.Role == llm.RoleUser {
+				last.Content = append(last.Content, input)
+				return messages, transcript.Citations
+			}
+			return append(messages, llm.Message{Role: llm.RoleUser, Content: []llm.ContentBlock{input}}), transcript.Citations
+		}
+	}
+
+	return mapMessagesToModelMessages(handle.GetMessageHistory()), nil
+}
+
+// saveTranscript stores the conversation of a completed task for the next task in the context.
+func (p *assetManagementAgent) saveTranscript(ctx context.Context, identity caller, contextID *string, transcript *Transcript) {
+	if p.Transcripts == nil || contextID == nil || *contextID == "" {
+		return
+	}
+	if err := p.Transcripts.Save(ctx, transcriptKey(identity, *contextID), transcript); err != nil {
+		fmt.Printf("failed to save transcript of context %s: %v\n", *contextID, err)
+	}
+}
+
+// stopAtLimit fails the task with a message telling the user which limit was reached.
+func (p *assetManagementAgent) stopAtLimit(handle taskmanager.TaskHandler, taskID string, contextID *string, budget *loopBudget, reason error) {
+	fmt.Printf("task %s stopped: %v\n", taskID, reason)
+
+	metadata := budget.metadata()
+	var limit *limitError
+	if errors.As(reason, &limit) {
+		metadata["limit"] = limit.Limit
+	}
+
+	p.endTask(handle, taskID, contextID, protocol.TaskStateFailed, reason.Error(), metadata)
+}
+
+// stopCanceled ends a task that was canceled while it was being processed. Streamed output is
+// closed so that the client keeps what it has received.
+func (p *assetManagementAgent) stopCanceled(handle taskmanager.TaskHandler, taskID string, contextID *string, stream *artifactStream, budget *loopBudget) {
+	fmt.Printf("task %s canceled\n", taskID)
+
+	if stream != nil && stream.Started() {
+		err := stream.Close(protocol.Artifact{
+			Name:        llm.StringPtr("Partial Response"),
+			Description: llm.StringPtr("Model output before the task was canceled"),
+		})
+		if err != nil {
+			fmt.Printf("failed to send partial artifact: %v\n", err)
+		}
+	}
+
+	p.endTask(handle, taskID, contextID, protocol.TaskStateCanceled, "The task was canceled.", budget.metadata())
+}
+
+// endTask moves the task to a final state with a message for the user.
+func (p *assetManagementAgent) endTask(handle taskmanager.TaskHandler, taskID string, contextID *string, state protocol.TaskState, text string, metadata map[string]interface{}) {
+	err := handle.UpdateTaskState(&taskID, state, &protocol.Message{
+		ContextID: contextID,
+		MessageID: protocol.GenerateMessageID(),
+		Role:      protocol.MessageRoleAgent,
+		Parts:     []protocol.Part{protocol.NewTextPart(text)},
+		Metadata:  metadata,
+	})
+	if err != nil {
+		fmt.Printf("failed to send %s event: %v\n", state, err)
+	}
+}
+
+// reasoningText returns the reasoning of a model turn that can be shown. Redacted reasoning is left out.
+func reasoningText(message llm.Message) []string {
+	var texts []string
+	for _, block := range message.Content {
+		if reasoning, ok := block.(*llm.ReasoningBlock); ok && reasoning.Text != "" {
+			texts = append(texts, reasoning.Text)
+		}
+	}
+	return texts
+}
+
 func boolPtr(b bool) *bool {
 	return &b
 }
@@ -233,33 +605,34 @@ func extractText(message protocol.Message) string {
 	return inputText
 }
 
-func mapMessagesToConverseMessages(messages []protocol.Message) []types.Message {
-	var convertedMessages = make([]types.Message, len(messages))
+func mapMessagesToModelMessages(messages []protocol.Message) []llm.Message {
+	var convertedMessages = make([]llm.Message, len(messages))
 	for i, message := range messages {
-		var content = make([]types.ContentBlock, len(message.Parts))
+		var content = make([]llm.ContentBlock, len(message.Parts))
 		for j, part := range message.Parts {
 			switch convertedPart := part.(type) {
 			case *protocol.TextPart:
-				content[j] = &types.ContentBlockMemberText{
-					Value: convertedPart.Text,
+				content[j] = &llm.TextBlock{
+					Text: convertedPart.Text,
 				}
 				continue
 			default:
-				fmt.Errorf("unsupported message part type")
+				fmt.Printf("unsupported message part type\n")
 
-				content[j] = &types.ContentBlockMemberText{
-					Value: "Unsupported message part type",
+				content[j] = &llm.TextBlock{
+					Text: "Unsupported message part type",
 				}
 				continue
 			}
 		}
 
+		role := llm.RoleUser
 		if message.Role == protocol.MessageRoleAgent {
-			message.Role = "assistant"
+			role = llm.RoleAssistant
 		}
 
-		convertedMessages[i] = types.Message{
-			Role:    types.ConversationRole(message.Role),
+		convertedMessages[i] = llm.Message{
+			Role:    role,
 			Content: content,
 		}
 
@@ -1,53 +1,155 @@
 package a2a
 
 import (
+	"fmt"
+	"fusion/internal/llm"
+	"fusion/internal/tools"
+	"runtime/debug"
+	"sort"
 	"trpc.group/trpc-go/trpc-a2a-go/server"
 )
 
-func GetAgentCard() server.AgentCard {
+// CardConfig holds the parts of the agent card that depend on the deployment.
+type CardConfig struct {
+	// URL is the public URL clients reach the agent at.
+	URL string `json:"url"`
+	// Version is the version of the agent. The version of the build is used when it is empty.
+	Version     string   `json:"version"`
+	InputModes  []string `json:"inputModes"`
+	OutputModes []string `json:"outputModes"`
+	// Skills are the skills listed on the card. Every registered tool is listed when it is empty.
+	Skills []SkillConfig `json:"skills"`
+	// SecuritySchemes are the ways a caller can authenticate. A caller may use any of them.
+	SecuritySchemes map[string]server.SecurityScheme `json:"-"`
+}
+
+// SkillConfig lists a tool as a skill. Fields left empty are taken from the tool.
+type SkillConfig struct {
+	// ID is the name of the tool the skill is backed by.
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Examples    []string `json:"examples"`
+}
+
+var DefaultCardConfig = CardConfig{
+	URL:         "http://localhost:8080",
+	InputModes:  []string{"text"},
+	OutputModes: []string{"text"},
+}
+
+// NewAgentCard describes the agent with the tools of its registry. It fails when a configured skill
+// is not backed by a registered tool.
+func NewAgentCard(registry *tools.Registry, config CardConfig) (server.AgentCard, error) {
+	skillConfigs := config.Skills
+	if len(skillConfigs) == 0 {
+		for _, registration := range registry.Registrations() {
+			skillConfigs = append(skillConfigs, SkillConfig{ID: registration.Name})
+		}
+	}
+
+	skills := make([]server.AgentSkill, 0, len(skillConfigs))
+	for _, skillConfig := range skillConfigs {
+		registration, ok := registry.Lookup(skillConfig.ID)
+		if !ok {
+			return server.AgentCard{}, fmt.Errorf("skill %s is not backed by a registered tool", skillConfig.ID)
+		}
+		skills = append(skills, newSkill(registration, skillConfig, config))
+	}
+
+	version := config.Version
+	if version == "" {
+		version = buildVersion()
+	}
+
+	var schemes []string
+	for name := range config.SecuritySchemes {
+		schemes = append(schemes, name)
+	}
+	sort.Strings(schemes)
+	var security []map[string][]string
+	for _, name := range schemes {
+		security = append(security, map[string][]string{name: {}})
+	}
+
 	agentCard := server.AgentCard{
 		Name:        "Asset Management",
 		Description: "An agent that can answer questions related to a customer's managed assets.",
-		URL:         "http://localhost:8080",
-		Version:     "1.0.0",
+		URL:         config.URL,
+		Version:     version,
 		Provider: &server.AgentProvider{
 			Organization: "n-able",
 		},
 		Capabilities: server.AgentCapabilities{
 			Streaming:              boolPtr(true),
-			PushNotifications:      boolPtr(false),
+			PushNotifications:      boolPtr(true),
 			StateTransitionHistory: boolPtr(true),
 		},
-		DefaultInputModes:  []string{"text"},
-		DefaultOutputModes: []string{"text"},
-		Skills: []server.AgentSkill{
-			{
-				ID:          "query_schema",
-				Name:        "Query Schema",
-				Description: stringPtr("Returns a partial GraphQL Schema that can be used to construct queries and mutations for an API that supports searching for managed assets and returning details regarding their operating systems, hardware and more."),
-				InputModes:  []string{"text"},
-				OutputModes: []string{"text"},
-			},
-			{
-				ID:          "execute_query",
-				Name:        "Execute Query",
-				Description: stringPtr("Executes a GraphQL Query using the n-able public API. Provides support for sophisticated searching of assets."),
-				InputModes:  []string{"text"},
-				OutputModes: []string{"text"},
-			},
-			{
-				ID:          "knowledge_query",
-				Name:        "Knowledge Query",
-				Description: stringPtr("Finds the most relevant knowledge article for a user's questions about managed assets"),
-				InputModes:  []string{"text"},
-				OutputModes: []string{"text"},
-			},
-		},
+		SecuritySchemes:    config.SecuritySchemes,
+		Security:           security,
+		DefaultInputModes:  config.InputModes,
+		DefaultOutputModes: config.OutputModes,
+		Skills:             skills,
 	}
 
-	return agentCard
+	return agentCard, nil
+}
+
+func newSkill(registration tools.Registration, skillConfig SkillConfig, config CardConfig) server.AgentSkill {
+	skill := server.AgentSkill{
+		ID:          registration.Name,
+		Name:        registration.Metadata.DisplayName,
+		Description: llm.StringPtr(registration.Schema.Description),
+		Tags:        registration.Metadata.Tags,
+		Examples:    skillConfig.Examples,
+		InputModes:  config.InputModes,
+		OutputModes: config.OutputModes,
+	}
+	if skill.Name == "" {
+		skill.Name = registration.Name
+	}
+	if skillConfig.Name != "" {
+		skill.Name = skillConfig.Name
+	}
+	if skillConfig.Description != "" {
+		skill.Description = llm.StringPtr(skillConfig.Description)
+	}
+	if skillConfig.Tags != nil {
+		skill.Tags = skillConfig.Tags
+	}
+	return skill
 }
 
-func stringPtr(s string) *string {
-	return &s
+// buildVersion returns the module version the agent was built from, or the VCS revision for builds of
+// a checkout.
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+
+	var revision string
+	modified := false
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision == "" {
+		return "dev"
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return revision
 }
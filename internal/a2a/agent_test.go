@@ -0,0 +1,190 @@
+package a2a
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"fusion/internal/llm"
+	"fusion/internal/tools"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"trpc.group/trpc-go/trpc-a2a-go/protocol"
+	"trpc.group/trpc-go/trpc-a2a-go/taskmanager"
+)
+
+// recordingHandle keeps the states and artifacts of one task.
+type recordingHandle struct {
+	taskmanager.TaskHandler
+	history   []protocol.Message
+	mu        sync.Mutex
+	states    []protocol.TaskState
+	artifacts []protocol.Artifact
+}
+
+func (h *recordingHandle) GetMessageHistory() []protocol.Message {
+	return h.history
+}
+
+func (h *recordingHandle) UpdateTaskState(taskID *string, state protocol.TaskState, message *protocol.Message) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.states = append(h.states, state)
+	return nil
+}
+
+func (h *recordingHandle) AddArtifact(taskID *string, artifact protocol.Artifact, isFinal bool, needMoreData bool) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.artifacts = append(h.artifacts, artifact)
+	return nil
+}
+
+func (h *recordingHandle) text() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var text strings.Builder
+	for _, artifact := range h.artifacts {
+		for _, part := range artifact.Parts {
+			if textPart, ok := part.(protocol.TextPart); ok {
+				text.WriteString(textPart.Text)
+			}
+		}
+	}
+	return text.String()
+}
+
+// taskModels passes every request to the fake model of the task it belongs to, found by the
+// question the task was started with.
+type taskModels map[string]*llm.FakeModel
+
+func (m taskModels) model(request *llm.Request) (*llm.FakeModel, error) {
+	question := request.Messages[0].Text()
+	model, ok := m[question]
+	if !ok {
+		return nil, fmt.Errorf("no model for question %q", question)
+	}
+	return model, nil
+}
+
+func (m taskModels) Chat(ctx context.Context, request *llm.Request) (*llm.Response, error) {
+	model, err := m.model(request)
+	if err != nil {
+		return nil, err
+	}
+	return model.Chat(ctx, request)
+}
+
+func (m taskModels) ChatStream(ctx context.Context, request *llm.Request, onText func(text string) error) (*llm.Response, error) {
+	model, err := m.model(request)
+	if err != nil {
+		return nil, err
+	}
+	return model.ChatStream(ctx, request, onText)
+}
+
+// assetServer answers asset searches with an asset named after the token and organizations of the
+// request, and fails introspection so that the embedded schema is used.
+func assetServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request tools.GraphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("invalid GraphQL request: %v", err)
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		if request.OperationName == "IntrospectionQuery" {
+			http.Error(w, "introspection disabled", http.StatusServiceUnavailable)
+			return
+		}
+
+		// Give the other tasks time to run their tool calls at the same time.
+		time.Sleep(10 * time.Millisecond)
+
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		organization := ""
+		if start := strings.Index(request.Query, `inOrganizations: ["`); start >= 0 {
+			organization = request.Query[start+len(`inOrganizations: ["`):]
+			organization = organization[:strings.Index(organization, `"`)]
+		}
+		fmt.Fprintf(w, `{"data":{"assetSearch":{"totalCount":1,"edges":[{"node":{"id":"asset of %s in %s"}}]}}}`, token, organization)
+	}))
+}
+
+func TestConcurrentTasksKeepTheirOwnState(t *testing.T) {
+	server := assetServer(t)
+	defer server.Close()
+
+	client := tools.NewGraphQLClient(server.URL)
+	schemas := tools.NewSchemaProvider(client, nil, time.Hour)
+
+	const tasks = 8
+	models := taskModels{}
+	for i := 0; i < tasks; i++ {
+		models[fmt.Sprintf("question %d", i)] = llm.NewFakeModel(
+			llm.ToolUseResponse(fmt.Sprintf("call-%d", i), "execute_query", map[string]interface{}{
+				"query": "query { assetSearch(first: 5) { totalCount edges { node { id } } } }",
+			}),
+			llm.TextResponse(fmt.Sprintf("answer %d", i)),
+		)
+	}
+
+	agent, err := NewAgent(models, llm.DefaultConfig(), LoopLimits{}, false, nil, ContextLimits{}, nil, client, schemas, tools.DefaultPaginationLimits, &tools.DefaultQueryPolicy, nil)
+	if err != nil {
+		t.Fatalf("failed to create agent: %v", err)
+	}
+
+	handles := make([]*recordingHandle, tasks)
+	var wg sync.WaitGroup
+	for i := 0; i < tasks; i++ {
+		question := fmt.Sprintf("question %d", i)
+		handles[i] = &recordingHandle{history: []protocol.Message{
+			protocol.NewMessage(protocol.MessageRoleUser, []protocol.Part{&protocol.TextPart{Kind: protocol.KindText, Text: question}}),
+		}}
+		identity := caller{
+			ID:            fmt.Sprintf("caller-%d", i),
+			Token:         fmt.Sprintf("token-%d", i),
+			Organizations: []string{fmt.Sprintf("org-%d", i)},
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			agent.processRequest(context.Background(), question, "", identity, nil, fmt.Sprintf("task-%d", i), handles[i], i%2 == 0)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < tasks; i++ {
+		handle := handles[i]
+		if states := handle.states; len(states) == 0 || states[len(states)-1] != protocol.TaskStateCompleted {
+			t.Errorf("task %d ended in states %v, want completed", i, states)
+		}
+		if text, want := handle.text(), fmt.Sprintf("answer %d", i); text != want {
+			t.Errorf("task %d answered %q, want %q", i, text, want)
+		}
+
+		model := models[fmt.Sprintf("question %d", i)]
+		if len(model.Requests) != 2 {
+			t.Fatalf("task %d made %d model requests, want 2", i, len(model.Requests))
+		}
+		messages := model.Requests[1].Messages
+		var results []*llm.ToolResultBlock
+		for _, block := range messages[len(messages)-1].Content {
+			if result, ok := block.(*llm.ToolResultBlock); ok {
+				results = append(results, result)
+			}
+		}
+		if len(results) != 1 {
+			t.Fatalf("task %d got %d tool results, want 1", i, len(results))
+		}
+		if id, want := results[0].ToolUseID, fmt.Sprintf("call-%d", i); id != want {
+			t.Errorf("task %d got the result of %s, want %s", i, id, want)
+		}
+		if want := fmt.Sprintf("asset of token-%d in org-%d", i, i); !strings.Contains(results[0].ResultText(), want) {
+			t.Errorf("task %d got tool result %s, want %q", i, results[0].ResultText(), want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package a2a
+
+import (
+	"fusion/internal/llm"
+	"strings"
+	"time"
+	"trpc.group/trpc-go/trpc-a2a-go/protocol"
+	"trpc.group/trpc-go/trpc-a2a-go/taskmanager"
+)
+
+const (
+	// Every chunk sent rewrites the stored task and adds an artifact to it, so the text of a turn is
+	// sent in batches rather than token by token.
+	streamFlushInterval = 250 * time.Millisecond
+	streamFlushSize     = 512
+)
+
+// artifactStream sends the text of a model turn to streaming clients as chunks of one artifact. The
+// first chunk starts the artifact and every later chunk is appended to it. Text is held back until
+// enough of it has arrived or enough time has passed since the last chunk.
+type artifactStream struct {
+	handle     taskmanager.TaskHandler
+	taskID     string
+	artifactID string
+	text       strings.Builder
+	// sent is the length of the text that has been sent.
+	sent      int
+	lastFlush time.Time
+}
+
+func newArtifactStream(handle taskmanager.TaskHandler, taskID string) *artifactStream {
+	return &artifactStream{
+		handle:     handle,
+		taskID:     taskID,
+		artifactID: protocol.GenerateArtifactID(),
+	}
+}
+
+func (s *artifactStream) Write(text string) error {
+	s.text.WriteString(text)
+
+	pending := s.text.Len() - s.sent
+	if pending < streamFlushSize && time.Since(s.lastFlush) < streamFlushInterval {
+		return nil
+	}
+	return s.flush()
+}
+
+func (s *artifactStream) flush() error {
+	pending := s.text.String()[s.sent:]
+	if pending == "" {
+		return nil
+	}
+
+	err := s.handle.AddArtifact(&s.taskID, protocol.Artifact{
+		ArtifactID: s.artifactID,
+		Name:       llm.StringPtr("Response"),
+		Parts:      []protocol.Part{protocol.NewTextPart(pending)},
+	}, false, s.sent > 0)
+	if err != nil {
+		return err
+	}
+	s.sent = s.text.Len()
+	s.lastFlush = time.Now()
+	return nil
+}
+
+// Started reports whether the model has written any text, sent or not.
+func (s *artifactStream) Started() bool {
+	return s.text.Len() > 0
+}
+
+func (s *artifactStream) Text() string {
+	return s.text.String()
+}
+
+// Close sends the last chunk of the artifact with the text that was held back. Parts whose text
+// continues the streamed text are reduced to the text that was not sent yet.
+func (s *artifactStream) Close(artifact protocol.Artifact) error {
+	artifact.ArtifactID = s.artifactID
+
+	sentText := s.text.String()[:s.sent]
+	pending := s.text.String()[s.sent:]
+
+	var parts []protocol.Part
+	for _, part := range artifact.Parts {
+		if textPart, ok := part.(protocol.TextPart); ok && strings.HasPrefix(textPart.Text, s.Text()) {
+			if remaining := strings.TrimPrefix(textPart.Text, sentText); remaining != "" {
+				parts = append(parts, protocol.NewTextPart(remaining))
+			}
+			pending = ""
+			continue
+		}
+		parts = append(parts, part)
+	}
+	if pending != "" {
+		parts = append([]protocol.Part{protocol.NewTextPart(pending)}, parts...)
+	}
+	if len(parts) == 0 {
+		parts = []protocol.Part{protocol.NewTextPart("")}
+	}
+	artifact.Parts = parts
+
+	return s.handle.AddArtifact(&s.taskID, artifact, true, s.sent > 0)
+}
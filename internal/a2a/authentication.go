@@ -0,0 +1,242 @@
+package a2a
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fusion/internal/tools"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+	"trpc.group/trpc-go/trpc-a2a-go/auth"
+)
+
+// The authenticators check the bearer token of each A2A request. The token is kept on the
+// authenticated user as its access token, so that the tools can call the GraphQL API as the caller.
+
+// organizationsClaim is the claim that lists the organizations whose assets a caller may see. A
+// caller without it is only accepted when unscoped callers are allowed, and is then not limited to
+// any organizations.
+const organizationsClaim = "organizations"
+
+// caller is who a task is processed for.
+type caller struct {
+	ID    string
+	Token string
+	// Organizations are the organizations whose assets the caller may see, or nil when the caller
+	// is not limited to any.
+	Organizations []string
+}
+
+// scope identifies the caller together with the organizations it may see. What is kept for a caller,
+// such as the transcript of a context, is only given back to the same scope.
+func (c caller) scope() string {
+	return tools.CallerScope(c.ID, c.Organizations)
+}
+
+// StaticKeyAuthenticator accepts the bearer tokens listed in a key file.
+type StaticKeyAuthenticator struct {
+	// Keys maps each caller to its key.
+	Keys map[string]StaticKey
+}
+
+// StaticKey is written in the key file either as the token alone, or as an object that also lists
+// the organizations of the caller. A key without organizations is only accepted when unscoped
+// callers are allowed.
+type StaticKey struct {
+	Token         string   `json:"token"`
+	Organizations []string `json:"organizations"`
+}
+
+func (k *StaticKey) UnmarshalJSON(data []byte) error {
+	var token string
+	if err := json.Unmarshal(data, &token); err == nil {
+		*k = StaticKey{Token: token}
+		return nil
+	}
+
+	type staticKey StaticKey
+	return json.Unmarshal(data, (*staticKey)(k))
+}
+
+// NewStaticKeyAuthenticator reads a JSON file that maps each caller to its key.
+func NewStaticKeyAuthenticator(path string) (*StaticKeyAuthenticator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	var keys map[string]StaticKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("invalid key file %s: %w", path, err)
+	}
+	for caller, key := range keys {
+		if key.Token == "" {
+			return nil, fmt.Errorf("invalid key file %s: caller %s has no token", path, caller)
+		}
+	}
+	return &StaticKeyAuthenticator{Keys: keys}, nil
+}
+
+func (a *StaticKeyAuthenticator) Authenticate(r *http.Request) (*auth.User, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for caller, key := range a.Keys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key.Token)) == 1 {
+			var claims map[string]interface{}
+			if key.Organizations != nil {
+				claims = map[string]interface{}{organizationsClaim: key.Organizations}
+			}
+			return callerUser(caller, claims, token, time.Time{}), nil
+		}
+	}
+	return nil, auth.ErrInvalidToken
+}
+
+// JWKSAuthenticator accepts JWTs signed with one of the keys of a local JWKS file.
+type JWKSAuthenticator struct {
+	Keys     jwk.Set
+	Issuer   string
+	Audience string
+}
+
+// NewJWKSAuthenticator reads the keys of a JWKS file. The issuer and audience of a token are only
+// checked when they are set.
+func NewJWKSAuthenticator(path string, issuer string, audience string) (*JWKSAuthenticator, error) {
+	keys, err := jwk.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWKS file %s: %w", path, err)
+	}
+	if keys.Len() == 0 {
+		return nil, fmt.Errorf("invalid JWKS file %s: no keys", path)
+	}
+	return &JWKSAuthenticator{Keys: keys, Issuer: issuer, Audience: audience}, nil
+}
+
+func (a *JWKSAuthenticator) Authenticate(r *http.Request) (*auth.User, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []jwt.ParseOption{
+		jwt.WithKeySet(a.Keys, jws.WithInferAlgorithmFromKey(true)),
+		jwt.WithValidate(true),
+	}
+	if a.Issuer != "" {
+		options = append(options, jwt.WithIssuer(a.Issuer))
+	}
+	if a.Audience != "" {
+		options = append(options, jwt.WithAudience(a.Audience))
+	}
+
+	parsed, err := jwt.ParseString(token, options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", auth.ErrInvalidToken, err)
+	}
+	if parsed.Subject() == "" {
+		return nil, fmt.Errorf("%w: no subject", auth.ErrInvalidToken)
+	}
+
+	claims, err := parsed.AsMap(r.Context())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", auth.ErrInvalidToken, err)
+	}
+	return callerUser(parsed.Subject(), claims, token, parsed.Expiration()), nil
+}
+
+// NewAuthenticator returns an authenticator that accepts a caller known to any of the given
+// authenticators.
+func NewAuthenticator(authenticators ...auth.Provider) (auth.Provider, error) {
+	switch len(authenticators) {
+	case 0:
+		return nil, errors.New("no authenticator configured")
+	case 1:
+		return authenticators[0], nil
+	}
+	return auth.NewChainAuthProvider(authenticators...), nil
+}
+
+// callerFromContext returns the caller a request was authenticated as. A caller without the
+// organizations claim is denied unless unscoped is set.
+func callerFromContext(ctx context.Context, unscoped bool) (caller, error) {
+	user, ok := ctx.Value(auth.AuthUserKey).(*auth.User)
+	if !ok || user == nil || user.OAuth2Info == nil || user.OAuth2Info.AccessToken == "" {
+		return caller{}, errors.New("the request must be authenticated with a bearer token")
+	}
+
+	organizations, err := organizationsValue(user.Claims[organizationsClaim], true)
+	if err != nil {
+		return caller{}, fmt.Errorf("the %s claim of the caller is invalid: %w", organizationsClaim, err)
+	}
+	if organizations == nil && !unscoped {
+		return caller{}, fmt.Errorf("the caller has no %s claim, so the organizations whose assets it may see are unknown", organizationsClaim)
+	}
+	return caller{ID: user.ID, Token: user.OAuth2Info.AccessToken, Organizations: organizations}, nil
+}
+
+// organizationsValue reads a list of organization IDs from a claim or metadata value. It returns nil
+// when the value is missing.
+func organizationsValue(value interface{}, allowEmpty bool) ([]string, error) {
+	var organizations []string
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		organizations = append([]string{}, v...)
+	case []interface{}:
+		organizations = []string{}
+		for _, item := range v {
+			organization, ok := item.(string)
+			if !ok {
+				return nil, errors.New("organizations must be a list of IDs")
+			}
+			organizations = append(organizations, organization)
+		}
+	default:
+		return nil, errors.New("organizations must be a list of IDs")
+	}
+
+	for _, organization := range organizations {
+		if organization == "" {
+			return nil, errors.New("organization IDs must not be empty")
+		}
+	}
+	if len(organizations) == 0 && !allowEmpty {
+		return nil, errors.New("organizations must list at least one ID")
+	}
+	return organizations, nil
+}
+
+func callerUser(id string, claims map[string]interface{}, token string, expiry time.Time) *auth.User {
+	return &auth.User{
+		ID:     id,
+		Claims: claims,
+		OAuth2Info: &auth.OAuth2UserInfo{
+			AccessToken: token,
+			TokenType:   string(auth.TokenTypeBearer),
+			Expiry:      expiry,
+		},
+	}
+}
+
+func bearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get(auth.AuthHeaderName)
+	if header == "" {
+		return "", auth.ErrMissingToken
+	}
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, string(auth.TokenTypeBearer)) || token == "" {
+		return "", auth.ErrInvalidAuthHeader
+	}
+	return token, nil
+}
@@ -0,0 +1,237 @@
+package a2a
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"trpc.group/trpc-go/trpc-a2a-go/auth"
+)
+
+func writeFile(t *testing.T, name string, data string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func authorizedRequest(authorization string) *http.Request {
+	request, _ := http.NewRequest(http.MethodPost, "http://agent/", nil)
+	if authorization != "" {
+		request.Header.Set(auth.AuthHeaderName, authorization)
+	}
+	return request
+}
+
+// authenticatedCaller authenticates the request and reads the caller as the task processor does.
+func authenticatedCaller(authenticator auth.Provider, request *http.Request, unscoped bool) (caller, error) {
+	user, err := authenticator.Authenticate(request)
+	if err != nil {
+		return caller{}, err
+	}
+	return callerFromContext(context.WithValue(request.Context(), auth.AuthUserKey, user), unscoped)
+}
+
+func TestStaticKeyAuthenticator(t *testing.T) {
+	authenticator, err := NewStaticKeyAuthenticator(writeFile(t, "keys.json", `{
+		"scoped": {"token": "scoped-key", "organizations": ["org-b", "org-a"]},
+		"empty": {"token": "empty-key", "organizations": []},
+		"unscoped": "unscoped-key"
+	}`))
+	if err != nil {
+		t.Fatalf("failed to read key file: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		unscoped      bool
+		want          caller
+		err           string
+		errIs         error
+	}{
+		{
+			name:          "scoped key",
+			authorization: "Bearer scoped-key",
+			want:          caller{ID: "scoped", Token: "scoped-key", Organizations: []string{"org-b", "org-a"}},
+		},
+		{
+			name:          "scheme is not case sensitive",
+			authorization: "bearer scoped-key",
+			want:          caller{ID: "scoped", Token: "scoped-key", Organizations: []string{"org-b", "org-a"}},
+		},
+		{
+			name:          "no organizations",
+			authorization: "Bearer empty-key",
+			want:          caller{ID: "empty", Token: "empty-key", Organizations: []string{}},
+		},
+		{
+			name:          "unscoped key denied",
+			authorization: "Bearer unscoped-key",
+			err:           "has no organizations claim",
+		},
+		{
+			name:          "unscoped key allowed",
+			authorization: "Bearer unscoped-key",
+			unscoped:      true,
+			want:          caller{ID: "unscoped", Token: "unscoped-key"},
+		},
+		{name: "unknown key", authorization: "Bearer other-key", errIs: auth.ErrInvalidToken},
+		{name: "no token", errIs: auth.ErrMissingToken},
+		{name: "other scheme", authorization: "Basic scoped-key", errIs: auth.ErrInvalidAuthHeader},
+		{name: "empty token", authorization: "Bearer ", errIs: auth.ErrInvalidAuthHeader},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := authenticatedCaller(authenticator, authorizedRequest(test.authorization), test.unscoped)
+			checkCaller(t, got, err, test.want, test.err, test.errIs)
+		})
+	}
+}
+
+func TestStaticKeyAuthenticatorRejectsKeysWithoutToken(t *testing.T) {
+	_, err := NewStaticKeyAuthenticator(writeFile(t, "keys.json", `{"caller": {"organizations": ["org-a"]}}`))
+	if err == nil || !strings.Contains(err.Error(), "caller caller has no token") {
+		t.Errorf("got error %v, want the caller without token", err)
+	}
+}
+
+func TestJWKSAuthenticator(t *testing.T) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	signingKey, err := jwk.FromRaw(private)
+	if err != nil {
+		t.Fatalf("failed to create signing key: %v", err)
+	}
+	signingKey.Set(jwk.KeyIDKey, "key-1")
+	publicKey, err := signingKey.PublicKey()
+	if err != nil {
+		t.Fatalf("failed to create public key: %v", err)
+	}
+	keys := jwk.NewSet()
+	keys.AddKey(publicKey)
+	data, err := json.Marshal(keys)
+	if err != nil {
+		t.Fatalf("failed to marshal JWKS: %v", err)
+	}
+
+	authenticator, err := NewJWKSAuthenticator(writeFile(t, "jwks.json", string(data)), "https://issuer", "fusion")
+	if err != nil {
+		t.Fatalf("failed to read JWKS file: %v", err)
+	}
+
+	sign := func(claims map[string]interface{}) string {
+		token := jwt.New()
+		for name, value := range claims {
+			if err := token.Set(name, value); err != nil {
+				t.Fatalf("failed to set claim %s: %v", name, err)
+			}
+		}
+		signed, err := jwt.Sign(token, jwt.WithKey(jwa.RS256, signingKey))
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return string(signed)
+	}
+	valid := func(extra map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{
+			jwt.SubjectKey:    "caller",
+			jwt.IssuerKey:     "https://issuer",
+			jwt.AudienceKey:   "fusion",
+			jwt.ExpirationKey: time.Now().Add(time.Hour),
+		}
+		for name, value := range extra {
+			if value == nil {
+				delete(claims, name)
+			} else {
+				claims[name] = value
+			}
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name     string
+		claims   map[string]interface{}
+		unscoped bool
+		want     []string
+		err      string
+		errIs    error
+	}{
+		{name: "organizations claim", claims: valid(map[string]interface{}{organizationsClaim: []string{"org-a"}}), want: []string{"org-a"}},
+		{name: "no organizations claim", claims: valid(nil), err: "has no organizations claim"},
+		{name: "unscoped", claims: valid(nil), unscoped: true},
+		{name: "invalid organizations claim", claims: valid(map[string]interface{}{organizationsClaim: "org-a"}), err: "organizations claim of the caller is invalid"},
+		{name: "empty organization", claims: valid(map[string]interface{}{organizationsClaim: []string{""}}), err: "must not be empty"},
+		{name: "other issuer", claims: valid(map[string]interface{}{jwt.IssuerKey: "https://other"}), errIs: auth.ErrInvalidToken},
+		{name: "other audience", claims: valid(map[string]interface{}{jwt.AudienceKey: "other"}), errIs: auth.ErrInvalidToken},
+		{name: "expired", claims: valid(map[string]interface{}{jwt.ExpirationKey: time.Now().Add(-time.Hour)}), errIs: auth.ErrInvalidToken},
+		{name: "no subject", claims: valid(map[string]interface{}{jwt.SubjectKey: nil}), errIs: auth.ErrInvalidToken},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			token := sign(test.claims)
+			got, err := authenticatedCaller(authenticator, authorizedRequest("Bearer "+token), test.unscoped)
+
+			want := caller{ID: "caller", Token: token, Organizations: test.want}
+			checkCaller(t, got, err, want, test.err, test.errIs)
+		})
+	}
+
+	t.Run("other key", func(t *testing.T) {
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatalf("failed to generate key: %v", err)
+		}
+		signed, err := jwt.Sign(jwt.New(), jwt.WithKey(jwa.RS256, other))
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		_, err = authenticatedCaller(authenticator, authorizedRequest("Bearer "+string(signed)), true)
+		checkCaller(t, caller{}, err, caller{}, "", auth.ErrInvalidToken)
+	})
+}
+
+func TestCallerFromContextRequiresAToken(t *testing.T) {
+	contexts := map[string]context.Context{
+		"no user":  context.Background(),
+		"no token": context.WithValue(context.Background(), auth.AuthUserKey, &auth.User{ID: "caller"}),
+	}
+	for name, ctx := range contexts {
+		if _, err := callerFromContext(ctx, true); err == nil {
+			t.Errorf("%s: caller was accepted", name)
+		}
+	}
+}
+
+func checkCaller(t *testing.T, got caller, err error, want caller, wantErr string, wantErrIs error) {
+	t.Helper()
+	switch {
+	case wantErrIs != nil:
+		if !errors.Is(err, wantErrIs) {
+			t.Errorf("got error %v, want %v", err, wantErrIs)
+		}
+	case wantErr != "":
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("got error %v, want %q", err, wantErr)
+		}
+	case err != nil:
+		t.Errorf("caller was denied: %v", err)
+	case fmt.Sprintf("%#v", got) != fmt.Sprintf("%#v", want):
+		t.Errorf("got caller %#v, want %#v", got, want)
+	}
+}
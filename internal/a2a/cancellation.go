@@ -0,0 +1,104 @@
+package a2a
+
+import (
+	"context"
+	"sync"
+	"time"
+	"trpc.group/trpc-go/trpc-a2a-go/protocol"
+	"trpc.group/trpc-go/trpc-a2a-go/taskmanager"
+)
+
+// cancelWait is how long a tasks/cancel request waits for the agent to stop the task.
+const cancelWait = 10 * time.Second
+
+// runningTasks holds a cancel function for every task the agent is processing.
+type runningTasks struct {
+	mu    sync.Mutex
+	tasks map[string]*runningTask
+}
+
+type runningTask struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// start returns the context the task is processed with. finish must be called once the task has
+// reached a final state.
+func (r *runningTasks) start(ctx context.Context, taskID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	task := &runningTask{cancel: cancel, done: make(chan struct{})}
+
+	r.mu.Lock()
+	if r.tasks == nil {
+		r.tasks = map[string]*runningTask{}
+	}
+	r.tasks[taskID] = task
+	r.mu.Unlock()
+
+	return ctx, func() {
+		r.mu.Lock()
+		if r.tasks[taskID] == task {
+			delete(r.tasks, taskID)
+		}
+		r.mu.Unlock()
+		cancel()
+		close(task.done)
+	}
+}
+
+// cancel cancels the context of a running task. It returns a channel that is closed once the task
+// has stopped, and false when the task is not running.
+func (r *runningTasks) cancel(taskID string) (<-chan struct{}, bool) {
+	r.mu.Lock()
+	task, ok := r.tasks[taskID]
+	r.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	task.cancel()
+	return task.done, true
+}
+
+// CancellingTaskManager stops the model and tool calls of a task when the task is canceled. The
+// task managers of the A2A library only mark the task as canceled and leave it running. Only the
+// caller who created a task can cancel it.
+type CancellingTaskManager struct {
+	taskmanager.TaskManager
+	Agent  *assetManagementAgent
+	Owners *TaskOwners
+}
+
+func NewCancellingTaskManager(manager taskmanager.TaskManager, agent *assetManagementAgent, owners *TaskOwners) *CancellingTaskManager {
+	return &CancellingTaskManager{TaskManager: manager, Agent: agent, Owners: owners}
+}
+
+func (m *CancellingTaskManager) OnCancelTask(ctx context.Context, params protocol.TaskIDParams) (*protocol.Task, error) {
+	if err := m.Owners.Check(ctx, params.ID); err != nil {
+		return nil, err
+	}
+
+	done, ok := m.Agent.running.cancel(params.ID)
+	if !ok {
+		return m.TaskManager.OnCancelTask(ctx, params)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(cancelWait):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	task, err := m.TaskManager.OnGetTask(ctx, protocol.TaskQueryParams{ID: params.ID})
+	if err != nil {
+		return nil, err
+	}
+	if task.Status.State == protocol.TaskStateCanceled {
+		return task, nil
+	}
+
+	// The task either finished before it could be stopped, in which case it cannot be canceled, or
+	// it is still stopping and is marked as canceled here.
+	return m.TaskManager.OnCancelTask(ctx, params)
+}
@@ -0,0 +1,22 @@
+package a2a
+
+import (
+	"fusion/internal/citation"
+	"trpc.group/trpc-go/trpc-a2a-go/protocol"
+)
+
+// responseParts returns the parts of the final artifact. When the text cites knowledge articles with
+// [n] markers it is followed by a data part listing them.
+func responseParts(text string, citations *citation.Citations) []protocol.Part {
+	parts := []protocol.Part{protocol.NewTextPart(text)}
+	if citations == nil {
+		return parts
+	}
+
+	cited := citations.Referenced(text)
+	if len(cited) == 0 {
+		return parts
+	}
+
+	return append(parts, protocol.NewDataPart(map[string]interface{}{"citations": cited}))
+}
@@ -0,0 +1,235 @@
+package a2a
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"fusion/internal/llm"
+	"fusion/internal/tools"
+	"strings"
+)
+
+// ContextLimits keeps the conversation within the model's context window. Sizes are estimated
+// tokens and a zero limit is not enforced.
+type ContextLimits struct {
+	// MaxResultTokens is the size above which a tool result is truncated. The rest can be read with
+	// the fetch_result tool.
+	MaxResultTokens int `json:"maxResultTokens"`
+	// SummaryThreshold is the size of the conversation above which older turns are summarised.
+	SummaryThreshold int `json:"summaryThreshold"`
+	// KeepRecentTokens is how much of the most recent conversation is kept as it is when older turns
+	// are summarised.
+	KeepRecentTokens int `json:"keepRecentTokens"`
+}
+
+var DefaultContextLimits = ContextLimits{
+	MaxResultTokens:  4000,
+	SummaryThreshold: 60000,
+	KeepRecentTokens: 20000,
+}
+
+// ResultChunkSize is the number of characters of a truncated result given to the model at a time.
+// It leaves room for the note that says how to read the rest.
+func (l ContextLimits) ResultChunkSize() int {
+	return l.MaxResultTokens * 3
+}
+
+const summaryPrompt = `
+	"You summarise the earlier part of a conversation between a customer and an IT Technician agent that uses tools to look up the customer's assets." +
+	"Keep the customer's requests, the answers given, the facts found with tools such as asset names, identifiers and counts, and any open questions." +
+	"Leave out the GraphQL schema and anything that can be looked up again. Reply with the summary only."
+`
+
+// truncatedMarker starts the note added to a truncated result, which is never truncated again.
+const truncatedMarker = "\n\n[Result truncated: "
+
+// summaryBlockChars is the most of each content block given to the model when summarising.
+const summaryBlockChars = 2000
+
+type contextManager struct {
+	limits  ContextLimits
+	model   llm.ChatModel
+	results tools.ResultStore
+	// scope is the caller scope of the task. Stored results can only be read by the same scope.
+	scope string
+}
+
+// prepare truncates oversized tool results and, once the conversation is over the summary threshold,
+// replaces its older turns with a summary. It returns the usage of the summary call.
+func (m *contextManager) prepare(ctx context.Context, taskID string, request *llm.Request) llm.Usage {
+	if m.limits.MaxResultTokens > 0 {
+		for i := range request.Messages {
+			m.truncateResults(ctx, taskID, &request.Messages[i])
+		}
+	}
+
+	if m.limits.SummaryThreshold <= 0 {
+		return llm.Usage{}
+	}
+	size := tools.EstimateTokens(request.System) + tokensOf(request.Messages)
+	if size <= m.limits.SummaryThreshold {
+		return llm.Usage{}
+	}
+
+	boundary := m.summaryBoundary(request.Messages)
+	if boundary < 1 {
+		fmt.Printf("Context Manager: task %s conversation is ~%d tokens but has no earlier turns to summarise\n", taskID, size)
+		return llm.Usage{}
+	}
+
+	summary, usage, err := m.summarise(ctx, request, request.Messages[:boundary])
+	if err != nil {
+		fmt.Printf("Context Manager: task %s failed to summarise the conversation: %v\n", taskID, err)
+		return usage
+	}
+
+	summaryMessage := llm.Message{
+		Role:    llm.RoleUser,
+		Content: []llm.ContentBlock{&llm.TextBlock{Text: "Summary of the earlier conversation:\n" + summary}},
+	}
+	fmt.Printf("Context Manager: task %s summarised %d of %d messages, conversation reduced from ~%d to ~%d tokens\n",
+		taskID, boundary, len(request.Messages), size, size-tokensOf(request.Messages[:boundary])+estimateMessageTokens(summaryMessage))
+
+	request.Messages = append([]llm.Message{summaryMessage}, request.Messages[boundary:]...)
+	return usage
+}
+
+// truncateResults shortens the tool results of a message that are over the limit. The full result
+// is stored so that the model can read the rest.
+func (m *contextManager) truncateResults(ctx context.Context, taskID string, message *llm.Message) {
+	for i, block := range message.Content {
+		result, ok := block.(*llm.ToolResultBlock)
+		if !ok {
+			continue
+		}
+		text := result.ResultText()
+		if tools.EstimateTokens(text) <= m.limits.MaxResultTokens || strings.Contains(text, truncatedMarker) {
+			continue
+		}
+
+		chunk, _ := tools.ResultChunk(text, 0, m.limits.ResultChunkSize())
+		note := fmt.Sprintf("%sshowing %d of %d characters.]", truncatedMarker, len(chunk), len(text))
+		if m.results != nil {
+			handle, err := m.results.Put(ctx, m.scope, text)
+			if err != nil {
+				fmt.Printf("Context Manager: task %s failed to store tool result %s: %v\n", taskID, result.ToolUseID, err)
+			} else {
+				note = fmt.Sprintf("%sshowing %d of %d characters. Call fetch_result with handle %q and offset %d to read more.]", truncatedMarker, len(chunk), len(text), handle, len(chunk))
+			}
+		}
+
+		fmt.Printf("Context Manager: task %s truncated tool result %s from ~%d to ~%d tokens\n", taskID, result.ToolUseID, tools.EstimateTokens(text), tools.EstimateTokens(chunk+note))
+		message.Content[i] = &llm.ToolResultBlock{
+			ToolUseID: result.ToolUseID,
+			Text:      chunk + note,
+			IsError:   result.IsError,
+		}
+	}
+}
+
+// summaryBoundary returns the index of the first message kept when summarising. The kept messages
+// start with an assistant turn, so that no tool result is separated from its tool use and the summary
+// can be given as the user message before it.
+func (m *contextManager) summaryBoundary(messages []llm.Message) int {
+	keep := len(messages)
+	size := 0
+	for keep > 0 {
+		size += estimateMessageTokens(messages[keep-1])
+		if size > m.limits.KeepRecentTokens {
+			break
+		}
+		keep--
+	}
+
+	for i := keep; i < len(messages); i++ {
+		if i > 0 && messages[i].Role == llm.RoleAssistant {
+			return i
+		}
+	}
+	for i := keep - 1; i > 0; i-- {
+		if messages[i].Role == llm.RoleAssistant {
+			return i
+		}
+	}
+	return 0
+}
+
+func (m *contextManager) summarise(ctx context.Context, request *llm.Request, messages []llm.Message) (string, llm.Usage, error) {
+	var temperature float32 = 0
+	response, err := m.model.Chat(ctx, &llm.Request{
+		Model:  request.Model,
+		System: summaryPrompt,
+		Messages: []llm.Message{{
+			Role:    llm.RoleUser,
+			Content: []llm.ContentBlock{&llm.TextBlock{Text: renderConversation(messages)}},
+		}},
+		Temperature: &temperature,
+		MaxTokens:   request.MaxTokens,
+	})
+	if err != nil {
+		return "", llm.Usage{}, err
+	}
+
+	summary := response.Message.Text()
+	if summary == "" {
+		return "", response.Usage, fmt.Errorf("the model returned an empty summary")
+	}
+	return summary, response.Usage, nil
+}
+
+// renderConversation writes the conversation as text for the summary call.
+func renderConversation(messages []llm.Message) string {
+	var builder strings.Builder
+	for _, message := range messages {
+		speaker := "Customer"
+		if message.Role == llm.RoleAssistant {
+			speaker = "Agent"
+		}
+		for _, block := range message.Content {
+			switch b := block.(type) {
+			case *llm.TextBlock:
+				fmt.Fprintf(&builder, "%s: %s\n\n", speaker, clip(b.Text))
+			case *llm.ToolUseBlock:
+				input, _ := json.Marshal(b.Input)
+				fmt.Fprintf(&builder, "Agent used the %s tool with %s\n\n", b.Name, clip(string(input)))
+			case *llm.ToolResultBlock:
+				fmt.Fprintf(&builder, "Tool result: %s\n\n", clip(b.ResultText()))
+			}
+		}
+	}
+	return builder.String()
+}
+
+func clip(text string) string {
+	chunk, _ := tools.ResultChunk(text, 0, summaryBlockChars)
+	if len(chunk) < len(text) {
+		return chunk + "..."
+	}
+	return chunk
+}
+
+func estimateMessageTokens(message llm.Message) int {
+	size := 0
+	for _, block := range message.Content {
+		switch b := block.(type) {
+		case *llm.TextBlock:
+			size += tools.EstimateTokens(b.Text)
+		case *llm.ToolUseBlock:
+			input, _ := json.Marshal(b.Input)
+			size += tools.EstimateTokens(b.Name) + tools.EstimateTokens(string(input))
+		case *llm.ToolResultBlock:
+			size += tools.EstimateTokens(b.ResultText())
+		case *llm.ReasoningBlock:
+			size += tools.EstimateTokens(b.Text) + tools.EstimateTokens(b.Signature) + tools.EstimateTokens(string(b.Redacted))
+		}
+	}
+	return size
+}
+
+func tokensOf(messages []llm.Message) int {
+	size := 0
+	for _, message := range messages {
+		size += estimateMessageTokens(message)
+	}
+	return size
+}
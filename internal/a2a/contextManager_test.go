@@ -0,0 +1,48 @@
+package a2a
+
+import (
+	"context"
+	"fusion/internal/llm"
+	"fusion/internal/tools"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTruncatedResultsCanOnlyBeFetchedByTheirCaller(t *testing.T) {
+	client, _ := newRedisClient(t)
+	results := &tools.RedisResultStore{Client: client, TTL: time.Hour}
+	limits := ContextLimits{MaxResultTokens: 10}
+
+	owner := &tools.Invocation{TaskID: "task-a", CallerID: "caller-a", Organizations: []string{"org-a"}}
+	manager := &contextManager{limits: limits, results: results, scope: owner.Scope()}
+	message := &llm.Message{Role: llm.RoleUser, Content: []llm.ContentBlock{
+		&llm.ToolResultBlock{ToolUseID: "call", Text: strings.Repeat("asset ", 100)},
+	}}
+	manager.truncateResults(context.Background(), owner.TaskID, message)
+
+	truncated := message.Content[0].(*llm.ToolResultBlock).Text
+	match := regexp.MustCompile(`handle "([^"]+)"`).FindStringSubmatch(truncated)
+	if match == nil {
+		t.Fatalf("truncated result %q has no handle", truncated)
+	}
+	fetch := tools.NewFetchResultTool(results, limits.ResultChunkSize())
+	call := &llm.ToolUseBlock{ID: "fetch", Name: fetch.Name, Input: map[string]interface{}{"handle": match[1]}}
+
+	if _, err := fetch.Call(tools.WithInvocation(context.Background(), owner), call); err != nil {
+		t.Errorf("owner failed to fetch the result: %v", err)
+	}
+
+	others := []*tools.Invocation{
+		{TaskID: "task-b", CallerID: "caller-b", Organizations: []string{"org-a"}},
+		// The same caller limited to other organizations may not read it either.
+		{TaskID: "task-c", CallerID: "caller-a", Organizations: []string{"org-b"}},
+	}
+	for _, other := range others {
+		_, err := fetch.Call(tools.WithInvocation(context.Background(), other), call)
+		if err == nil || !strings.Contains(err.Error(), "the handle is unknown") {
+			t.Errorf("%s fetched the result of another caller, got error %v", other.TaskID, err)
+		}
+	}
+}
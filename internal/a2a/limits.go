@@ -0,0 +1,97 @@
+package a2a
+
+import (
+	"fmt"
+	"fusion/internal/llm"
+	"time"
+)
+
+// LoopLimits bounds the work done for a single task. A zero limit is not enforced.
+type LoopLimits struct {
+	// MaxTurns is the number of model calls, each of which may use tools.
+	MaxTurns        int
+	MaxInputTokens  int
+	MaxOutputTokens int
+	MaxDuration     time.Duration
+	// MaxParallelTools is the number of tool calls of one model turn that run at the same time.
+	MaxParallelTools int
+}
+
+var DefaultLoopLimits = LoopLimits{
+	MaxTurns:         15,
+	MaxInputTokens:   500000,
+	MaxOutputTokens:  50000,
+	MaxDuration:      5 * time.Minute,
+	MaxParallelTools: 4,
+}
+
+// limitError reports the limit a task ran into.
+type limitError struct {
+	Limit   string
+	Message string
+}
+
+func (e *limitError) Error() string {
+	return e.Message
+}
+
+// loopBudget tracks the turns and tokens used by a task against its limits.
+type loopBudget struct {
+	limits      LoopLimits
+	started     time.Time
+	turns       int
+	usage       llm.Usage
+	stopReasons []string
+}
+
+func newLoopBudget(limits LoopLimits) *loopBudget {
+	return &loopBudget{limits: limits, started: time.Now()}
+}
+
+func (b *loopBudget) record(response *llm.Response) {
+	b.turns++
+	b.addUsage(response.Usage)
+	b.stopReasons = append(b.stopReasons, string(response.StopReason))
+}
+
+// addUsage counts tokens used outside of the model turns, such as for summaries.
+func (b *loopBudget) addUsage(usage llm.Usage) {
+	b.usage.InputTokens += usage.InputTokens
+	b.usage.OutputTokens += usage.OutputTokens
+	b.usage.TotalTokens += usage.TotalTokens
+}
+
+// check returns an error when another model call would exceed a limit.
+func (b *loopBudget) check() error {
+	if b.limits.MaxTurns > 0 && b.turns >= b.limits.MaxTurns {
+		return &limitError{Limit: "turns", Message: fmt.Sprintf("I stopped after %d model turns, the most allowed for a request, without reaching an answer.", b.turns)}
+	}
+	if b.limits.MaxInputTokens > 0 && b.usage.InputTokens >= b.limits.MaxInputTokens {
+		return &limitError{Limit: "inputTokens", Message: fmt.Sprintf("I stopped after reading %d tokens, the most allowed for a request, without reaching an answer.", b.usage.InputTokens)}
+	}
+	if b.limits.MaxOutputTokens > 0 && b.usage.OutputTokens >= b.limits.MaxOutputTokens {
+		return &limitError{Limit: "outputTokens", Message: fmt.Sprintf("I stopped after writing %d tokens, the most allowed for a request, without reaching an answer.", b.usage.OutputTokens)}
+	}
+	if b.limits.MaxDuration > 0 && time.Since(b.started) >= b.limits.MaxDuration {
+		return b.timeout()
+	}
+	return nil
+}
+
+func (b *loopBudget) timeout() error {
+	return &limitError{Limit: "duration", Message: fmt.Sprintf("I stopped after %s, the longest a request may run, without reaching an answer.", b.limits.MaxDuration)}
+}
+
+// metadata describes the usage of the task and the stop reason of every model turn, for the final
+// task message or artifact.
+func (b *loopBudget) metadata() map[string]interface{} {
+	return map[string]interface{}{
+		"usage": map[string]interface{}{
+			"turns":        b.turns,
+			"inputTokens":  b.usage.InputTokens,
+			"outputTokens": b.usage.OutputTokens,
+			"elapsed":      time.Since(b.started).Round(time.Millisecond).String(),
+		},
+		"stopReasons": append([]string{}, b.stopReasons...),
+	}
+}
@@ -0,0 +1,575 @@
+package a2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"trpc.group/trpc-go/trpc-a2a-go/auth"
+	"trpc.group/trpc-go/trpc-a2a-go/protocol"
+	"trpc.group/trpc-go/trpc-a2a-go/taskmanager"
+)
+
+// Push notifications send the state changes and finished artifacts of a task to the webhook a client
+// set for it, so that clients do not have to keep a stream open while a task runs. The events of a
+// task are delivered one at a time in the order they happened. Each request is signed with a JWT whose key
+// is published at the JWKS endpoint of the server, and the state of every delivery is kept in Redis.
+
+const (
+	// pushTokenHeader carries the token the client set with the webhook, so that it can tell its
+	// notifications apart.
+	pushTokenHeader = "X-A2A-Notification-Token"
+	// pushDeliveryHeader identifies a notification. Retries of a notification have the same ID.
+	pushDeliveryHeader = "X-A2A-Notification-ID"
+	// pushDeliveriesMetadataKey is the metadata key of the delivery state returned with the push
+	// notification config of a task.
+	pushDeliveriesMetadataKey = "deliveries"
+
+	redisPushDeliveryPrefix = "pushDelivery:"
+	pushRequestTimeout      = 10 * time.Second
+	// pushConfigCacheTTL is how long the webhook of a task, or the fact that it has none, is used
+	// before it is read again. A webhook set with this agent is used straight away.
+	pushConfigCacheTTL = 30 * time.Second
+	// pushQueueLimit is the most events of a task waiting to be delivered. The oldest are dropped when
+	// a webhook falls further behind.
+	pushQueueLimit = 50
+)
+
+// PushRetry sets how often a notification is sent before it is given up. The wait between attempts
+// doubles from InitialBackoff up to MaxBackoff.
+type PushRetry struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+var DefaultPushRetry = PushRetry{
+	MaxAttempts:    5,
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+}
+
+func (r PushRetry) backoff(attempt int) time.Duration {
+	backoff := r.InitialBackoff
+	for i := 1; i < attempt && backoff < r.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if r.MaxBackoff > 0 && backoff > r.MaxBackoff {
+		backoff = r.MaxBackoff
+	}
+	return backoff
+}
+
+// PushDelivery is the state of one notification.
+type PushDelivery struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	// State is pending while the notification is being sent, then delivered or failed.
+	State      string `json:"state"`
+	Attempts   int    `json:"attempts"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+	CreatedAt  string `json:"createdAt"`
+	UpdatedAt  string `json:"updatedAt"`
+}
+
+const (
+	pushDeliveryPending   = "pending"
+	pushDeliveryDelivered = "delivered"
+	pushDeliveryFailed    = "failed"
+)
+
+type PushNotifier struct {
+	// Tasks holds the push notification config of each task.
+	Tasks  taskmanager.TaskManager
+	Signer *auth.PushNotificationAuthenticator
+	// Deliveries keeps the delivery state of each task for TTL.
+	Deliveries *redis.Client
+	TTL        time.Duration
+	Retry      PushRetry
+	Client     *http.Client
+	// PrivateWebhooks lets webhooks be on loopback, link-local and private addresses, for trying
+	// notifications locally. Such webhooks are rejected otherwise, so that the agent cannot be used to
+	// reach the services beside it, such as the cloud metadata endpoint.
+	PrivateWebhooks bool
+
+	mu      sync.Mutex
+	queues  map[string]*pushQueue
+	configs map[string]cachedPushConfig
+}
+
+// cachedPushConfig is the webhook of a task, or nil when the task has none.
+type cachedPushConfig struct {
+	config    *protocol.PushNotificationConfig
+	expiresAt time.Time
+}
+
+// pushQueue holds the events of a task that are waiting to be delivered.
+type pushQueue struct {
+	events []pushEvent
+}
+
+type pushEvent struct {
+	taskID  string
+	kind    string
+	payload interface{}
+	// final is set for the last event of a task.
+	final bool
+}
+
+// NewPushNotifier returns a notifier without a task manager, which must be set once the task manager
+// has been created with the agent.
+func NewPushNotifier(signer *auth.PushNotificationAuthenticator, deliveries *redis.Client, ttl time.Duration, retry PushRetry) *PushNotifier {
+	notifier := &PushNotifier{
+		Signer:     signer,
+		Deliveries: deliveries,
+		TTL:        ttl,
+		Retry:      retry,
+	}
+	// The address of a webhook is checked again when it is connected to, as its host name may resolve
+	// to another address than when it was set, and the webhook may redirect.
+	dialer := &net.Dialer{Timeout: pushRequestTimeout, Control: notifier.checkDial}
+	notifier.Client = &http.Client{
+		Timeout:   pushRequestTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+	}
+	return notifier
+}
+
+// SetConfig sets the webhook of a task from the configuration of a message/send request.
+func (n *PushNotifier) SetConfig(ctx context.Context, taskID string, config protocol.PushNotificationConfig) error {
+	if err := n.validate(config); err != nil {
+		return err
+	}
+	if _, err := n.Tasks.OnPushNotificationSet(ctx, protocol.TaskPushNotificationConfig{TaskID: taskID, PushNotificationConfig: config}); err != nil {
+		return err
+	}
+	n.cacheConfig(taskID, &config)
+	return nil
+}
+
+func (n *PushNotifier) cacheConfig(taskID string, config *protocol.PushNotificationConfig) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.configs == nil {
+		n.configs = map[string]cachedPushConfig{}
+	}
+	n.configs[taskID] = cachedPushConfig{config: config, expiresAt: time.Now().Add(pushConfigCacheTTL)}
+}
+
+// config returns the webhook of a task, or nil when it has none.
+func (n *PushNotifier) config(ctx context.Context, taskID string) *protocol.PushNotificationConfig {
+	n.mu.Lock()
+	cached, ok := n.configs[taskID]
+	n.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.config
+	}
+
+	// The task manager reports a task without a config with an error.
+	var config *protocol.PushNotificationConfig
+	if taskConfig, err := n.Tasks.OnPushNotificationGet(ctx, protocol.TaskIDParams{ID: taskID}); err == nil {
+		config = &taskConfig.PushNotificationConfig
+	}
+	n.cacheConfig(taskID, config)
+	return config
+}
+
+// handle returns a task handler that notifies the webhook of the task of every event.
+func (n *PushNotifier) handle(handle taskmanager.TaskHandler, contextID string) taskmanager.TaskHandler {
+	return &notifyingHandle{TaskHandler: handle, notifier: n, contextID: contextID}
+}
+
+// notify queues an event for delivery. A task's events are delivered by one goroutine at a time, which
+// exits once the queue is empty.
+func (n *PushNotifier) notify(taskID string, kind string, payload interface{}, final bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.queues == nil {
+		n.queues = map[string]*pushQueue{}
+	}
+	queue, running := n.queues[taskID]
+	if !running {
+		queue = &pushQueue{}
+		n.queues[taskID] = queue
+	}
+	if len(queue.events) >= pushQueueLimit {
+		fmt.Printf("Push Notifications: task %s has %d events waiting, dropping the oldest %s event\n", taskID, len(queue.events), queue.events[0].kind)
+		queue.events = queue.events[1:]
+	}
+	queue.events = append(queue.events, pushEvent{taskID: taskID, kind: kind, payload: payload, final: final})
+	if !running {
+		go n.deliverQueue(taskID, queue)
+	}
+}
+
+func (n *PushNotifier) deliverQueue(taskID string, queue *pushQueue) {
+	for {
+		n.mu.Lock()
+		if len(queue.events) == 0 {
+			delete(n.queues, taskID)
+			n.mu.Unlock()
+			return
+		}
+		event := queue.events[0]
+		queue.events = queue.events[1:]
+		n.mu.Unlock()
+
+		n.deliver(context.Background(), event)
+		if event.final {
+			n.mu.Lock()
+			delete(n.configs, taskID)
+			n.mu.Unlock()
+		}
+	}
+}
+
+// deliver sends an event to the webhook of its task, if it has one.
+func (n *PushNotifier) deliver(ctx context.Context, event pushEvent) {
+	config := n.config(ctx, event.taskID)
+	if config == nil {
+		return
+	}
+	payload, err := json.Marshal(event.payload)
+	if err != nil {
+		fmt.Printf("Push Notifications: task %s failed to serialise %s event: %v\n", event.taskID, event.kind, err)
+		return
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	delivery := PushDelivery{ID: uuid.NewString(), Event: event.kind, State: pushDeliveryPending, CreatedAt: now, UpdatedAt: now}
+	n.saveDelivery(ctx, event.taskID, delivery)
+	for delivery.Attempts < max(n.Retry.MaxAttempts, 1) {
+		if delivery.Attempts > 0 {
+			time.Sleep(n.Retry.backoff(delivery.Attempts))
+		}
+		delivery.Attempts++
+
+		statusCode, err := n.send(ctx, *config, delivery.ID, payload)
+		delivery.StatusCode = statusCode
+		delivery.UpdatedAt = time.Now().UTC().Format(time.RFC3339Nano)
+		if err == nil {
+			delivery.State = pushDeliveryDelivered
+			delivery.Error = ""
+			n.saveDelivery(ctx, event.taskID, delivery)
+			return
+		}
+
+		delivery.Error = err.Error()
+		if !retryable(statusCode) {
+			break
+		}
+		fmt.Printf("Push Notifications: task %s %s event attempt %d failed: %v\n", event.taskID, event.kind, delivery.Attempts, err)
+		n.saveDelivery(ctx, event.taskID, delivery)
+	}
+
+	fmt.Printf("Push Notifications: task %s %s event not delivered after %d attempts: %s\n", event.taskID, event.kind, delivery.Attempts, delivery.Error)
+	delivery.State = pushDeliveryFailed
+	n.saveDelivery(ctx, event.taskID, delivery)
+}
+
+func (n *PushNotifier) send(ctx context.Context, config protocol.PushNotificationConfig, deliveryID string, payload []byte) (int, error) {
+	// The payload is signed for every attempt, as receivers reject signatures that are too old.
+	authorization, err := n.Signer.CreateAuthorizationHeader(payload)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sign notification: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(auth.AuthHeaderName, authorization)
+	request.Header.Set(pushDeliveryHeader, deliveryID)
+	if config.Token != "" {
+		request.Header.Set(pushTokenHeader, config.Token)
+	}
+
+	response, err := n.Client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("webhook responded with %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
+
+// retryable reports whether a failed notification is worth sending again. Requests the webhook
+// rejects are not, apart from timeouts and rate limits.
+func retryable(statusCode int) bool {
+	if statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return statusCode < 400 || statusCode >= 500
+}
+
+func (n *PushNotifier) saveDelivery(ctx context.Context, taskID string, delivery PushDelivery) {
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		fmt.Printf("Push Notifications: task %s failed to serialise delivery %s: %v\n", taskID, delivery.ID, err)
+		return
+	}
+
+	key := redisPushDeliveryPrefix + taskID
+	pipe := n.Deliveries.TxPipeline()
+	pipe.HSet(ctx, key, delivery.ID, data)
+	pipe.Expire(ctx, key, n.TTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		fmt.Printf("Push Notifications: task %s failed to store delivery %s: %v\n", taskID, delivery.ID, err)
+	}
+}
+
+// deliveries returns the state of the notifications of a task, oldest first.
+func (n *PushNotifier) deliveries(ctx context.Context, taskID string) ([]PushDelivery, error) {
+	values, err := n.Deliveries.HGetAll(ctx, redisPushDeliveryPrefix+taskID).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deliveries: %w", err)
+	}
+
+	deliveries := make([]PushDelivery, 0, len(values))
+	for _, value := range values {
+		var delivery PushDelivery
+		if err := json.Unmarshal([]byte(value), &delivery); err != nil {
+			return nil, fmt.Errorf("failed to parse delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		created, _ := time.Parse(time.RFC3339Nano, deliveries[i].CreatedAt)
+		other, _ := time.Parse(time.RFC3339Nano, deliveries[j].CreatedAt)
+		return created.Before(other)
+	})
+	return deliveries, nil
+}
+
+// pushBlockedNetworks are the networks webhooks cannot be on besides those the net package
+// classifies: the shared address space, which holds the metadata endpoint of some clouds, and the
+// networks that do not route to another host.
+var pushBlockedNetworks = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("192.0.0.0/24"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return network
+}
+
+func (n *PushNotifier) validate(config protocol.PushNotificationConfig) error {
+	webhook, err := url.Parse(config.URL)
+	if err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+		return errors.New("push notification URL must be an absolute http or https URL")
+	}
+	if n.PrivateWebhooks {
+		return nil
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(webhook.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".internal") {
+		return fmt.Errorf("push notification URL must not be on %s", host)
+	}
+	if ip := net.ParseIP(host); ip != nil && blockedAddress(ip) {
+		return fmt.Errorf("push notification URL must not be on the private address %s", ip)
+	}
+	return nil
+}
+
+// checkDial refuses connections to webhooks on blocked addresses.
+func (n *PushNotifier) checkDial(network string, address string, _ syscall.RawConn) error {
+	if n.PrivateWebhooks {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || blockedAddress(ip) {
+		return fmt.Errorf("webhook address %s is not allowed", host)
+	}
+	return nil
+}
+
+func blockedAddress(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsPrivate() ||
+		ip.IsUnspecified() || ip.IsMulticast() || ip.IsInterfaceLocalMulticast() {
+		return true
+	}
+	for _, network := range pushBlockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyingHandle sends the state changes and finished artifacts of a task to its webhook once they
+// have been stored. The chunks of a streamed artifact are held back and sent as one artifact with the
+// last chunk.
+type notifyingHandle struct {
+	taskmanager.TaskHandler
+	notifier  *PushNotifier
+	contextID string
+
+	mu     sync.Mutex
+	state  protocol.TaskState
+	chunks map[string][]protocol.Part
+}
+
+func (h *notifyingHandle) UpdateTaskState(taskID *string, state protocol.TaskState, message *protocol.Message) error {
+	if err := h.TaskHandler.UpdateTaskState(taskID, state, message); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	changed := state != h.state
+	h.state = state
+	h.mu.Unlock()
+	if !changed {
+		return nil
+	}
+
+	status := protocol.TaskStatus{State: state, Message: message, Timestamp: time.Now().UTC().Format(time.RFC3339)}
+	event := protocol.NewTaskStatusUpdateEvent(*taskID, h.contextID, status, isFinalState(state))
+	h.notifier.notify(*taskID, event.Kind, &event, isFinalState(state))
+	return nil
+}
+
+func (h *notifyingHandle) AddArtifact(taskID *string, artifact protocol.Artifact, isFinal bool, needMoreData bool) error {
+	if err := h.TaskHandler.AddArtifact(taskID, artifact, isFinal, needMoreData); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	if h.chunks == nil {
+		h.chunks = map[string][]protocol.Part{}
+	}
+	parts := append(h.chunks[artifact.ArtifactID], artifact.Parts...)
+	if !isFinal {
+		h.chunks[artifact.ArtifactID] = parts
+		h.mu.Unlock()
+		return nil
+	}
+	delete(h.chunks, artifact.ArtifactID)
+	h.mu.Unlock()
+
+	artifact.Parts = mergeTextParts(parts)
+	event := protocol.NewTaskArtifactUpdateEvent(*taskID, h.contextID, artifact, true)
+	h.notifier.notify(*taskID, event.Kind, &event, false)
+	return nil
+}
+
+// mergeTextParts joins the text parts that follow one another, which are the chunks of streamed text.
+func mergeTextParts(parts []protocol.Part) []protocol.Part {
+	var merged []protocol.Part
+	var text strings.Builder
+	pending := false
+	for _, part := range parts {
+		if textPart, ok := part.(protocol.TextPart); ok {
+			text.WriteString(textPart.Text)
+			pending = true
+			continue
+		}
+		if pending {
+			merged = append(merged, protocol.NewTextPart(text.String()))
+			text.Reset()
+			pending = false
+		}
+		merged = append(merged, part)
+	}
+	if pending {
+		merged = append(merged, protocol.NewTextPart(text.String()))
+	}
+	return merged
+}
+
+func isFinalState(state protocol.TaskState) bool {
+	switch state {
+	case protocol.TaskStateCompleted, protocol.TaskStateFailed, protocol.TaskStateCanceled, protocol.TaskStateRejected:
+		return true
+	}
+	return false
+}
+
+// PushTaskManager checks the webhooks clients set and returns the delivery state of a task's
+// notifications with its push notification config. Only the caller who created a task can set or
+// read its webhook. A task the client does not wait for keeps running once the client hangs up.
+type PushTaskManager struct {
+	taskmanager.TaskManager
+	Notifier *PushNotifier
+	Owners   *TaskOwners
+}
+
+func NewPushTaskManager(manager taskmanager.TaskManager, notifier *PushNotifier, owners *TaskOwners) *PushTaskManager {
+	return &PushTaskManager{TaskManager: manager, Notifier: notifier, Owners: owners}
+}
+
+func (m *PushTaskManager) OnSendMessage(ctx context.Context, request protocol.SendMessageParams) (*protocol.MessageResult, error) {
+	blocking := false
+	var push *protocol.PushNotificationConfig
+	if config := request.Configuration; config != nil {
+		blocking = config.Blocking != nil && *config.Blocking
+		push = config.PushNotificationConfig
+	}
+	// The task manager stores the events of the task with the context of the request, so the context
+	// must not end with the request.
+	if detached(blocking, push) {
+		ctx = context.WithoutCancel(ctx)
+	}
+	return m.TaskManager.OnSendMessage(ctx, request)
+}
+
+func (m *PushTaskManager) OnPushNotificationSet(ctx context.Context, params protocol.TaskPushNotificationConfig) (*protocol.TaskPushNotificationConfig, error) {
+	if err := m.Owners.Check(ctx, params.TaskID); err != nil {
+		return nil, err
+	}
+	if err := m.Notifier.validate(params.PushNotificationConfig); err != nil {
+		return nil, err
+	}
+	config, err := m.TaskManager.OnPushNotificationSet(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	m.Notifier.cacheConfig(params.TaskID, &config.PushNotificationConfig)
+	return config, nil
+}
+
+func (m *PushTaskManager) OnPushNotificationGet(ctx context.Context, params protocol.TaskIDParams) (*protocol.TaskPushNotificationConfig, error) {
+	if err := m.Owners.Check(ctx, params.ID); err != nil {
+		return nil, err
+	}
+	config, err := m.TaskManager.OnPushNotificationGet(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := m.Notifier.deliveries(ctx, params.ID)
+	if err != nil {
+		return nil, err
+	}
+	if config.Metadata == nil {
+		config.Metadata = map[string]interface{}{}
+	}
+	config.Metadata[pushDeliveriesMetadataKey] = deliveries
+	return config, nil
+}
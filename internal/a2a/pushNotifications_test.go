@@ -0,0 +1,339 @@
+package a2a
+
+import (
+	"context"
+	"encoding/json"
+	"fusion/internal/llm"
+	"fusion/internal/tools"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+	"trpc.group/trpc-go/trpc-a2a-go/auth"
+	"trpc.group/trpc-go/trpc-a2a-go/protocol"
+	"trpc.group/trpc-go/trpc-a2a-go/taskmanager"
+)
+
+func TestPushNotifierRejectsPrivateWebhooks(t *testing.T) {
+	notifier := NewPushNotifier(nil, nil, time.Hour, DefaultPushRetry)
+
+	for url, allowed := range map[string]bool{
+		"https://hooks.example.com/a2a":          true,
+		"http://203.0.113.10:8443/a2a":           true,
+		"ftp://hooks.example.com/a2a":            false,
+		"/a2a":                                   false,
+		"http://localhost:9000/a2a":              false,
+		"http://LOCALHOST./a2a":                  false,
+		"http://receiver.localhost/a2a":          false,
+		"http://metadata.google.internal/":       false,
+		"http://127.0.0.1/a2a":                   false,
+		"http://[::1]/a2a":                       false,
+		"http://169.254.169.254/latest/metadata": false,
+		"http://10.1.2.3/a2a":                    false,
+		"http://172.16.0.1/a2a":                  false,
+		"http://192.168.1.1/a2a":                 false,
+		"http://100.100.100.200/a2a":             false,
+		"http://0.0.0.0/a2a":                     false,
+		"http://[fe80::1]/a2a":                   false,
+		"http://[fd00::1]/a2a":                   false,
+	} {
+		err := notifier.validate(protocol.PushNotificationConfig{URL: url})
+		if allowed && err != nil {
+			t.Errorf("%s was rejected: %v", url, err)
+		}
+		if !allowed && err == nil {
+			t.Errorf("%s was allowed", url)
+		}
+	}
+
+	// A host name may resolve to a private address, so the address is checked again when connecting.
+	for address, allowed := range map[string]bool{
+		"203.0.113.10:443":   true,
+		"127.0.0.1:443":      false,
+		"169.254.169.254:80": false,
+		"[::1]:443":          false,
+		"10.0.0.1:443":       false,
+	} {
+		err := notifier.checkDial("tcp", address, nil)
+		if allowed && err != nil {
+			t.Errorf("dialing %s was refused: %v", address, err)
+		}
+		if !allowed && err == nil {
+			t.Errorf("dialing %s was allowed", address)
+		}
+	}
+
+	notifier.PrivateWebhooks = true
+	if err := notifier.validate(protocol.PushNotificationConfig{URL: "http://localhost:9000/a2a"}); err != nil {
+		t.Errorf("local webhook rejected when private webhooks are allowed: %v", err)
+	}
+	if err := notifier.checkDial("tcp", "127.0.0.1:9000", nil); err != nil {
+		t.Errorf("dialing a local webhook refused when private webhooks are allowed: %v", err)
+	}
+}
+
+// webhookTasks is a task manager that holds the push notification config of every task.
+type webhookTasks struct {
+	taskmanager.TaskManager
+	mu      sync.Mutex
+	configs map[string]protocol.PushNotificationConfig
+	reads   int
+}
+
+func (m *webhookTasks) OnPushNotificationGet(ctx context.Context, params protocol.TaskIDParams) (*protocol.TaskPushNotificationConfig, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reads++
+	config, ok := m.configs[params.ID]
+	if !ok {
+		return nil, taskmanager.ErrPushNotificationNotConfigured(params.ID)
+	}
+	return &protocol.TaskPushNotificationConfig{TaskID: params.ID, PushNotificationConfig: config}, nil
+}
+
+// webhook receives notifications and answers each with the next of its status codes, then with 200.
+type webhook struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	received []receivedNotification
+}
+
+type receivedNotification struct {
+	id      string
+	token   string
+	payload map[string]interface{}
+}
+
+func newWebhook(t *testing.T, statuses ...int) *webhook {
+	w := &webhook{statuses: statuses}
+	w.server = httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		var payload map[string]interface{}
+		if err := json.NewDecoder(request.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid notification: %v", err)
+		}
+		if request.Header.Get(auth.AuthHeaderName) == "" {
+			t.Error("notification is not signed")
+		}
+
+		w.mu.Lock()
+		w.received = append(w.received, receivedNotification{
+			id:      request.Header.Get(pushDeliveryHeader),
+			token:   request.Header.Get(pushTokenHeader),
+			payload: payload,
+		})
+		status := http.StatusOK
+		if len(w.statuses) > 0 {
+			status, w.statuses = w.statuses[0], w.statuses[1:]
+		}
+		w.mu.Unlock()
+		response.WriteHeader(status)
+	}))
+	t.Cleanup(w.server.Close)
+	return w
+}
+
+func (w *webhook) notifications() []receivedNotification {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]receivedNotification{}, w.received...)
+}
+
+func newTestPushNotifier(t *testing.T, tasks *webhookTasks) *PushNotifier {
+	signer := auth.NewPushNotificationAuthenticator()
+	if err := signer.GenerateKeyPair(); err != nil {
+		t.Fatalf("failed to create push notification key: %v", err)
+	}
+	client, _ := newRedisClient(t)
+
+	notifier := NewPushNotifier(signer, client, time.Hour, PushRetry{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	notifier.PrivateWebhooks = true
+	notifier.Tasks = tasks
+	return notifier
+}
+
+// waitForDeliveries waits until the notifications of a task have all been delivered or given up.
+func waitForDeliveries(t *testing.T, notifier *PushNotifier, taskID string, count int) []PushDelivery {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deliveries, err := notifier.deliveries(context.Background(), taskID)
+		if err != nil {
+			t.Fatalf("failed to read deliveries: %v", err)
+		}
+		done := len(deliveries) == count
+		for _, delivery := range deliveries {
+			done = done && delivery.State != pushDeliveryPending
+		}
+		if done {
+			return deliveries
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("deliveries of task %s did not finish: %+v", taskID, deliveries)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPushNotifierDeliversStateChangesAndFinishedArtifacts(t *testing.T) {
+	// The first notification fails twice before it is delivered.
+	receiver := newWebhook(t, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	tasks := &webhookTasks{configs: map[string]protocol.PushNotificationConfig{
+		"task": {URL: receiver.server.URL, Token: "client-token"},
+	}}
+	notifier := newTestPushNotifier(t, tasks)
+
+	taskID := "task"
+	handle := notifier.handle(&recordingHandle{}, "context")
+	handle.UpdateTaskState(&taskID, protocol.TaskStateWorking, nil)
+	handle.UpdateTaskState(&taskID, protocol.TaskStateWorking, nil)
+	handle.AddArtifact(&taskID, protocol.Artifact{ArtifactID: "answer", Parts: []protocol.Part{protocol.NewTextPart("Hello, ")}}, false, false)
+	handle.AddArtifact(&taskID, protocol.Artifact{ArtifactID: "answer", Parts: []protocol.Part{protocol.NewTextPart("world")}}, true, false)
+	handle.UpdateTaskState(&taskID, protocol.TaskStateCompleted, nil)
+
+	deliveries := waitForDeliveries(t, notifier, taskID, 3)
+	for i, attempts := range []int{3, 1, 1} {
+		if deliveries[i].State != pushDeliveryDelivered || deliveries[i].Attempts != attempts {
+			t.Errorf("delivery %d is %s after %d attempts, want delivered after %d", i, deliveries[i].State, deliveries[i].Attempts, attempts)
+		}
+	}
+
+	received := receiver.notifications()
+	if len(received) != 5 {
+		t.Fatalf("webhook received %d requests, want 5", len(received))
+	}
+	// Retries of a notification have the same ID.
+	if received[0].id != received[1].id || received[1].id != received[2].id || received[2].id == received[3].id {
+		t.Errorf("notification IDs are %s, %s, %s, %s", received[0].id, received[1].id, received[2].id, received[3].id)
+	}
+	if received[0].token != "client-token" {
+		t.Errorf("notification token is %q, want the client's", received[0].token)
+	}
+
+	// The repeated working state is sent once, and the chunks of the artifact are sent together.
+	if status := received[2].payload["status"].(map[string]interface{}); status["state"] != string(protocol.TaskStateWorking) {
+		t.Errorf("first notification is %v, want the working state", received[2].payload)
+	}
+	artifact := received[3].payload["artifact"].(map[string]interface{})
+	if parts := artifact["parts"].([]interface{}); len(parts) != 1 || parts[0].(map[string]interface{})["text"] != "Hello, world" {
+		t.Errorf("artifact notification has parts %v, want one part with the whole text", artifact["parts"])
+	}
+	if status := received[4].payload["status"].(map[string]interface{}); status["state"] != string(protocol.TaskStateCompleted) || received[4].payload["final"] != true {
+		t.Errorf("last notification is %v, want the final completed state", received[4].payload)
+	}
+
+	// The webhook is read once for all the notifications of the task.
+	if tasks.reads != 1 {
+		t.Errorf("webhook read %d times, want once", tasks.reads)
+	}
+}
+
+func TestPushNotifierGivesUpOnRejectedNotifications(t *testing.T) {
+	rejecting := newWebhook(t, http.StatusBadRequest)
+	failing := newWebhook(t, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	tasks := &webhookTasks{configs: map[string]protocol.PushNotificationConfig{
+		"rejected": {URL: rejecting.server.URL},
+		"failing":  {URL: failing.server.URL},
+	}}
+	notifier := newTestPushNotifier(t, tasks)
+
+	for taskID, attempts := range map[string]int{"rejected": 1, "failing": 3} {
+		notifier.notify(taskID, "status-update", map[string]string{"taskId": taskID}, true)
+		deliveries := waitForDeliveries(t, notifier, taskID, 1)
+		if deliveries[0].State != pushDeliveryFailed || deliveries[0].Attempts != attempts || deliveries[0].Error == "" {
+			t.Errorf("%s delivery is %+v, want failed after %d attempts", taskID, deliveries[0], attempts)
+		}
+	}
+}
+
+func TestPushNotifierDropsTheOldestEventsOfASlowWebhook(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var received []float64
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+		var payload map[string]float64
+		json.NewDecoder(request.Body).Decode(&payload)
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		mu.Lock()
+		received = append(received, payload["n"])
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	tasks := &webhookTasks{configs: map[string]protocol.PushNotificationConfig{"task": {URL: server.URL}}}
+	notifier := newTestPushNotifier(t, tasks)
+
+	// The first event is sent and held by the webhook while the others are queued.
+	notifier.notify("task", "status-update", map[string]int{"n": 0}, false)
+	<-started
+	events := pushQueueLimit + 10
+	for n := 1; n <= events; n++ {
+		notifier.notify("task", "status-update", map[string]int{"n": n}, n == events)
+	}
+	close(release)
+
+	waitForDeliveries(t, notifier, "task", pushQueueLimit+1)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != pushQueueLimit+1 || received[0] != 0 || received[1] != 11 || received[len(received)-1] != float64(events) {
+		t.Errorf("webhook received events %v, want 0 and the last %d in order", received, pushQueueLimit)
+	}
+}
+
+// gatedModel answers once it is released.
+type gatedModel struct {
+	*llm.FakeModel
+	release chan struct{}
+}
+
+func (m *gatedModel) Chat(ctx context.Context, request *llm.Request) (*llm.Response, error) {
+	<-m.release
+	return m.FakeModel.Chat(ctx, request)
+}
+
+func TestDetachedTaskOutlivesTheRequest(t *testing.T) {
+	model := &gatedModel{FakeModel: llm.NewFakeModel(llm.TextResponse("answer")), release: make(chan struct{})}
+	agent := &assetManagementAgent{Model: model, Registry: tools.NewRegistry(), UnscopedCallers: true}
+	tasks, err := taskmanager.NewMemoryTaskManager(agent)
+	if err != nil {
+		t.Fatalf("failed to create task manager: %v", err)
+	}
+	manager := NewPushTaskManager(tasks, nil, nil)
+
+	ctx, cancel := context.WithCancel(callerContext("caller-a"))
+	contextID := protocol.GenerateContextID()
+	result, err := manager.OnSendMessage(ctx, protocol.SendMessageParams{
+		Message: protocol.NewMessageWithContext(protocol.MessageRoleUser, []protocol.Part{&protocol.TextPart{Kind: protocol.KindText, Text: "question"}}, nil, &contextID),
+	})
+	if err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+	// The client hangs up as soon as it has the task.
+	cancel()
+
+	task, ok := result.Result.(*protocol.Task)
+	if !ok || task.Status.State != protocol.TaskStateSubmitted {
+		t.Fatalf("got %+v, want the submitted task", result.Result)
+	}
+	close(model.release)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for task.Status.State != protocol.TaskStateCompleted {
+		if time.Now().After(deadline) {
+			t.Fatalf("task is %s, want completed", task.Status.State)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if task, err = manager.OnGetTask(context.Background(), protocol.TaskQueryParams{ID: task.ID}); err != nil {
+			t.Fatalf("failed to get task: %v", err)
+		}
+	}
+	if len(task.Artifacts) != 1 || len(task.Artifacts[0].Parts) != 1 || task.Artifacts[0].Parts[0].(protocol.TextPart).Text != "answer" {
+		t.Errorf("task has artifacts %+v, want the answer", task.Artifacts)
+	}
+}
@@ -0,0 +1,186 @@
+package a2a
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRedis is a Redis server that keeps strings and hashes in memory. It speaks enough of the
+// protocol for the stores of this package, and ignores expiry.
+type fakeRedis struct {
+	listener net.Listener
+
+	mu      sync.Mutex
+	strings map[string]string
+	hashes  map[string]map[string]string
+}
+
+// newRedisClient starts a fake Redis server for the test and returns a client of it.
+func newRedisClient(t *testing.T) (*redis.Client, *fakeRedis) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start fake redis: %v", err)
+	}
+	server := &fakeRedis{listener: listener, strings: map[string]string{}, hashes: map[string]map[string]string{}}
+	go server.serve()
+
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String(), Protocol: 2})
+	t.Cleanup(func() {
+		client.Close()
+		listener.Close()
+	})
+	return client, server
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.serveConn(conn)
+	}
+}
+
+func (f *fakeRedis) serveConn(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	// The commands between MULTI and EXEC are queued and run together.
+	var queued [][]string
+	transaction := false
+	for {
+		command, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		var reply string
+		switch name := strings.ToUpper(command[0]); {
+		case name == "MULTI":
+			transaction, queued = true, nil
+			reply = "+OK\r\n"
+		case name == "EXEC":
+			reply = fmt.Sprintf("*%d\r\n", len(queued))
+			for _, queuedCommand := range queued {
+				reply += f.exec(queuedCommand)
+			}
+			transaction, queued = false, nil
+		case transaction:
+			queued = append(queued, command)
+			reply = "+QUEUED\r\n"
+		default:
+			reply = f.exec(command)
+		}
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+
+	command := make([]string, count)
+	for i := range command {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, value); err != nil {
+			return nil, err
+		}
+		command[i] = string(value[:size])
+	}
+	return command, nil
+}
+
+func (f *fakeRedis) exec(command []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch strings.ToUpper(command[0]) {
+	case "HELLO":
+		return "-ERR unknown command 'HELLO'\r\n"
+	case "PING":
+		return "+PONG\r\n"
+	case "CLIENT", "SELECT":
+		return "+OK\r\n"
+	case "SET":
+		for _, option := range command[3:] {
+			if strings.ToUpper(option) == "NX" {
+				if _, ok := f.strings[command[1]]; ok {
+					return "$-1\r\n"
+				}
+			}
+		}
+		f.strings[command[1]] = command[2]
+		return "+OK\r\n"
+	case "GET":
+		value, ok := f.strings[command[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulkString(value)
+	case "DEL":
+		deleted := 0
+		for _, key := range command[1:] {
+			if _, ok := f.strings[key]; ok {
+				deleted++
+			}
+			if _, ok := f.hashes[key]; ok {
+				deleted++
+			}
+			delete(f.strings, key)
+			delete(f.hashes, key)
+		}
+		return fmt.Sprintf(":%d\r\n", deleted)
+	case "EXPIRE", "PEXPIRE":
+		return ":1\r\n"
+	case "HSET":
+		hash, ok := f.hashes[command[1]]
+		if !ok {
+			hash = map[string]string{}
+			f.hashes[command[1]] = hash
+		}
+		added := 0
+		for i := 2; i+1 < len(command); i += 2 {
+			if _, ok := hash[command[i]]; !ok {
+				added++
+			}
+			hash[command[i]] = command[i+1]
+		}
+		return fmt.Sprintf(":%d\r\n", added)
+	case "HGETALL":
+		hash := f.hashes[command[1]]
+		reply := fmt.Sprintf("*%d\r\n", len(hash)*2)
+		for field, value := range hash {
+			reply += bulkString(field) + bulkString(value)
+		}
+		return reply
+	}
+	return fmt.Sprintf("-ERR unknown command '%s'\r\n", command[0])
+}
+
+func bulkString(value string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
@@ -0,0 +1,88 @@
+package a2a
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+	"trpc.group/trpc-go/trpc-a2a-go/protocol"
+	"trpc.group/trpc-go/trpc-a2a-go/taskmanager"
+)
+
+// TaskOwners keeps the caller who created each task, so that other callers cannot act on the task.
+type TaskOwners struct {
+	Client *redis.Client
+	// TTL is how long the owner of a task is kept. It should outlast the task, as a task without an
+	// owner cannot be acted on by anyone.
+	TTL time.Duration
+}
+
+const redisTaskOwnerPrefix = "taskOwner:"
+
+func NewTaskOwners(client *redis.Client, ttl time.Duration) *TaskOwners {
+	return &TaskOwners{Client: client, TTL: ttl}
+}
+
+// Claim records the caller as the owner of a task. A task that already has an owner, because a
+// message continues it, can only be claimed by the same caller.
+func (o *TaskOwners) Claim(ctx context.Context, taskID string, identity caller) error {
+	claimed, err := o.Client.SetNX(ctx, redisTaskOwnerPrefix+taskID, identity.ID, o.TTL).Result()
+	if err != nil {
+		return fmt.Errorf("failed to store the owner of task %s: %w", taskID, err)
+	}
+	if claimed {
+		return nil
+	}
+	return o.check(ctx, taskID, identity.ID)
+}
+
+// Check fails unless the caller of the request owns the task. The error is the one for a task that
+// does not exist, so that callers cannot find out about the tasks of others.
+func (o *TaskOwners) Check(ctx context.Context, taskID string) error {
+	// Only who the caller is matters here, not the organizations it may see.
+	identity, err := callerFromContext(ctx, true)
+	if err != nil {
+		return err
+	}
+	return o.check(ctx, taskID, identity.ID)
+}
+
+func (o *TaskOwners) check(ctx context.Context, taskID string, callerID string) error {
+	owner, err := o.Client.Get(ctx, redisTaskOwnerPrefix+taskID).Result()
+	if errors.Is(err, redis.Nil) {
+		return taskmanager.ErrTaskNotFound(taskID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the owner of task %s: %w", taskID, err)
+	}
+	if owner != callerID {
+		fmt.Printf("Task Owners: caller %s denied access to task %s\n", callerID, taskID)
+		return taskmanager.ErrTaskNotFound(taskID)
+	}
+	return nil
+}
+
+// OwnedTaskManager only lets the caller who created a task read it or subscribe to its events again.
+type OwnedTaskManager struct {
+	taskmanager.TaskManager
+	Owners *TaskOwners
+}
+
+func NewOwnedTaskManager(manager taskmanager.TaskManager, owners *TaskOwners) *OwnedTaskManager {
+	return &OwnedTaskManager{TaskManager: manager, Owners: owners}
+}
+
+func (m *OwnedTaskManager) OnGetTask(ctx context.Context, params protocol.TaskQueryParams) (*protocol.Task, error) {
+	if err := m.Owners.Check(ctx, params.ID); err != nil {
+		return nil, err
+	}
+	return m.TaskManager.OnGetTask(ctx, params)
+}
+
+func (m *OwnedTaskManager) OnResubscribe(ctx context.Context, params protocol.TaskIDParams) (<-chan protocol.StreamingMessageEvent, error) {
+	if err := m.Owners.Check(ctx, params.ID); err != nil {
+		return nil, err
+	}
+	return m.TaskManager.OnResubscribe(ctx, params)
+}
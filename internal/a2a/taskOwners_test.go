@@ -0,0 +1,102 @@
+package a2a
+
+import (
+	"context"
+	"testing"
+	"time"
+	"trpc.group/trpc-go/trpc-a2a-go/auth"
+	"trpc.group/trpc-go/trpc-a2a-go/protocol"
+	"trpc.group/trpc-go/trpc-a2a-go/taskmanager"
+)
+
+// storedTasks is a task manager that returns a task for every ID and records the calls that reach it.
+type storedTasks struct {
+	taskmanager.TaskManager
+	calls []string
+}
+
+func (m *storedTasks) OnGetTask(ctx context.Context, params protocol.TaskQueryParams) (*protocol.Task, error) {
+	m.calls = append(m.calls, "get "+params.ID)
+	return &protocol.Task{ID: params.ID, Status: protocol.TaskStatus{State: protocol.TaskStateCompleted}}, nil
+}
+
+func (m *storedTasks) OnResubscribe(ctx context.Context, params protocol.TaskIDParams) (<-chan protocol.StreamingMessageEvent, error) {
+	m.calls = append(m.calls, "resubscribe "+params.ID)
+	events := make(chan protocol.StreamingMessageEvent)
+	close(events)
+	return events, nil
+}
+
+// callerContext is the context of a request authenticated as the caller.
+func callerContext(id string, organizations ...string) context.Context {
+	var claims map[string]interface{}
+	if organizations != nil {
+		claims = map[string]interface{}{organizationsClaim: organizations}
+	}
+	return context.WithValue(context.Background(), auth.AuthUserKey, callerUser(id, claims, "token-"+id, time.Time{}))
+}
+
+func TestOwnedTaskManagerDeniesOtherCallers(t *testing.T) {
+	client, _ := newRedisClient(t)
+	owners := NewTaskOwners(client, time.Hour)
+	tasks := &storedTasks{}
+	manager := NewOwnedTaskManager(tasks, owners)
+	cancelling := NewCancellingTaskManager(manager, &assetManagementAgent{}, owners)
+
+	if err := owners.Claim(context.Background(), "task-a", caller{ID: "caller-a"}); err != nil {
+		t.Fatalf("failed to claim task: %v", err)
+	}
+
+	owner := callerContext("caller-a", "org-a")
+	if _, err := manager.OnGetTask(owner, protocol.TaskQueryParams{ID: "task-a"}); err != nil {
+		t.Errorf("owner could not get its task: %v", err)
+	}
+	if _, err := manager.OnResubscribe(owner, protocol.TaskIDParams{ID: "task-a"}); err != nil {
+		t.Errorf("owner could not resubscribe to its task: %v", err)
+	}
+
+	other := callerContext("caller-b", "org-a")
+	for name, call := range map[string]func(ctx context.Context, taskID string) error{
+		"get": func(ctx context.Context, taskID string) error {
+			_, err := manager.OnGetTask(ctx, protocol.TaskQueryParams{ID: taskID})
+			return err
+		},
+		"resubscribe": func(ctx context.Context, taskID string) error {
+			_, err := manager.OnResubscribe(ctx, protocol.TaskIDParams{ID: taskID})
+			return err
+		},
+		"cancel": func(ctx context.Context, taskID string) error {
+			_, err := cancelling.OnCancelTask(ctx, protocol.TaskIDParams{ID: taskID})
+			return err
+		},
+	} {
+		// Another caller is told the task does not exist, as it would be for a task that does not.
+		want := taskmanager.ErrTaskNotFound("task-a").Error()
+		if err := call(other, "task-a"); err == nil || err.Error() != want {
+			t.Errorf("%s: another caller got %v, want %s", name, err, want)
+		}
+		if err := call(owner, "task-missing"); err == nil {
+			t.Errorf("%s: a task without an owner was given", name)
+		}
+	}
+
+	if want := []string{"get task-a", "resubscribe task-a"}; len(tasks.calls) != len(want) || tasks.calls[0] != want[0] || tasks.calls[1] != want[1] {
+		t.Errorf("task manager got %v, want %v", tasks.calls, want)
+	}
+}
+
+func TestTaskOwnersClaim(t *testing.T) {
+	client, _ := newRedisClient(t)
+	owners := NewTaskOwners(client, time.Hour)
+
+	if err := owners.Claim(context.Background(), "task-a", caller{ID: "caller-a"}); err != nil {
+		t.Fatalf("failed to claim task: %v", err)
+	}
+	// A message that continues the task claims it again.
+	if err := owners.Claim(context.Background(), "task-a", caller{ID: "caller-a"}); err != nil {
+		t.Errorf("owner could not continue its task: %v", err)
+	}
+	if err := owners.Claim(context.Background(), "task-a", caller{ID: "caller-b"}); err == nil {
+		t.Error("another caller continued the task")
+	}
+}
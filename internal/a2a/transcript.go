@@ -0,0 +1,75 @@
+package a2a
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"fusion/internal/citation"
+	"fusion/internal/llm"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// Transcript is the model conversation of a context, tool uses and results included. Citations are
+// the knowledge articles numbered in the context, so that a later task cites them with the same
+// [n] markers as the answers before it.
+type Transcript struct {
+	Messages  []llm.Message       `json:"messages"`
+	Citations []citation.Citation `json:"citations,omitempty"`
+}
+
+// TranscriptStore keeps the transcript of a context so that later tasks in the context can build
+// on earlier results. Transcripts are kept under a key built from the context and the caller, so
+// that only the caller who started a context can continue it. Load reports false when there is no
+// transcript under the key.
+type TranscriptStore interface {
+	Load(ctx context.Context, key string) (*Transcript, bool, error)
+	Save(ctx context.Context, key string, transcript *Transcript) error
+}
+
+// transcriptKey is the key of the transcript of a context for a caller. A caller who reuses the
+// context ID of another caller, or the same caller with other organizations, starts a new
+// conversation.
+func transcriptKey(identity caller, contextID string) string {
+	return identity.scope() + ":" + contextID
+}
+
+type RedisTranscriptStore struct {
+	Client *redis.Client
+	// TTL is how long a transcript is kept after the last task in its context.
+	TTL time.Duration
+}
+
+const redisTranscriptPrefix = "transcript:"
+
+func NewRedisTranscriptStore(client *redis.Client, ttl time.Duration) *RedisTranscriptStore {
+	return &RedisTranscriptStore{Client: client, TTL: ttl}
+}
+
+func (s *RedisTranscriptStore) Load(ctx context.Context, key string) (*Transcript, bool, error) {
+	data, err := s.Client.Get(ctx, redisTranscriptPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read transcript: %w", err)
+	}
+
+	var transcript Transcript
+	if err := json.Unmarshal(data, &transcript); err != nil {
+		return nil, false, fmt.Errorf("failed to parse transcript: %w", err)
+	}
+	return &transcript, true, nil
+}
+
+func (s *RedisTranscriptStore) Save(ctx context.Context, key string, transcript *Transcript) error {
+	data, err := json.Marshal(transcript)
+	if err != nil {
+		return fmt.Errorf("failed to serialise transcript: %w", err)
+	}
+	if err := s.Client.Set(ctx, redisTranscriptPrefix+key, data, s.TTL).Err(); err != nil {
+		return fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return nil
+}
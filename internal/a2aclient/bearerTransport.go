@@ -0,0 +1,39 @@
+package a2aclient
+
+import (
+	"context"
+	"net/http"
+	"trpc.group/trpc-go/trpc-a2a-go/auth"
+)
+
+type bearerTokenKey struct{}
+
+// WithBearerToken returns a context whose A2A requests are sent with the token. A gateway uses it to
+// call the agent as each of its own callers.
+func WithBearerToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, bearerTokenKey{}, token)
+}
+
+// BearerTransport adds a bearer token to the requests of an A2A client. The token in the request
+// context is used when there is one, and Token otherwise.
+type BearerTransport struct {
+	Base  http.RoundTripper
+	Token string
+}
+
+func NewBearerClient(token string) *http.Client {
+	return &http.Client{Transport: &BearerTransport{Base: http.DefaultTransport, Token: token}}
+}
+
+func (t *BearerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	token := t.Token
+	if contextToken, ok := request.Context().Value(bearerTokenKey{}).(string); ok && contextToken != "" {
+		token = contextToken
+	}
+
+	if token != "" {
+		request = request.Clone(request.Context())
+		request.Header.Set(auth.AuthHeaderName, string(auth.TokenTypeBearer)+" "+token)
+	}
+	return t.Base.RoundTrip(request)
+}
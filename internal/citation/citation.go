@@ -0,0 +1,123 @@
+package citation
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"sync"
+	"trpc.group/trpc-go/trpc-a2a-go/protocol"
+)
+
+type Citation struct {
+	Number int    `json:"number"`
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+}
+
+// Citations collects the knowledge articles returned to the model in a context. Every article is
+// numbered once, in the order it was first returned, so the model can refer to it as [n].
+type Citations struct {
+	mu    sync.Mutex
+	items []Citation
+}
+
+// NewCitations continues the numbering of the citations of earlier tasks in the context.
+func NewCitations(citations []Citation) *Citations {
+	return &Citations{items: append([]Citation(nil), citations...)}
+}
+
+func (c *Citations) Add(id string, title string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, citation := range c.items {
+		if citation.ID == id {
+			return citation.Number
+		}
+	}
+
+	citation := Citation{Number: len(c.items) + 1, ID: id, Title: title}
+	c.items = append(c.items, citation)
+	return citation.Number
+}
+
+func (c *Citations) List() []Citation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Citation(nil), c.items...)
+}
+
+// Referenced returns the citations whose [n] marker appears in the text.
+func (c *Citations) Referenced(text string) []Citation {
+	markers := markers(text)
+
+	var referenced []Citation
+	for _, citation := range c.List() {
+		if markers[citation.Number] {
+			referenced = append(referenced, citation)
+		}
+	}
+	return referenced
+}
+
+func Marker(number int) string {
+	return fmt.Sprintf("[%d]", number)
+}
+
+var (
+	markerPattern = regexp.MustCompile(`\[([0-9]+)\]`)
+	// codePattern matches fenced code blocks, including one left open at the end of the text, and
+	// code spans. Brackets in code are indexes rather than citations.
+	codePattern = regexp.MustCompile("(?s)```.*?(?:```|$)|`[^`\n]*`")
+)
+
+// markers returns the numbers of the [n] markers in the text. A marker directly after a letter,
+// digit or underscore, such as items[1], is an index and not a citation.
+func markers(text string) map[int]bool {
+	text = codePattern.ReplaceAllLiteralString(text, " ")
+
+	numbers := map[int]bool{}
+	for _, match := range markerPattern.FindAllStringSubmatchIndex(text, -1) {
+		if start := match[0]; start > 0 && isWordByte(text[start-1]) {
+			continue
+		}
+		number, err := strconv.Atoi(text[match[2]:match[3]])
+		if err != nil {
+			continue
+		}
+		numbers[number] = true
+	}
+	return numbers
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+// FromParts reads the citations list of a final artifact received from the agent.
+func FromParts(parts []protocol.Part) []Citation {
+	var citations []Citation
+
+	for _, part := range parts {
+		dataPart, ok := part.(*protocol.DataPart)
+		if !ok {
+			continue
+		}
+
+		data, err := json.Marshal(dataPart.Data)
+		if err != nil {
+			continue
+		}
+
+		var list struct {
+			Citations []Citation `json:"citations"`
+		}
+		if err := json.Unmarshal(data, &list); err == nil {
+			citations = append(citations, list.Citations...)
+		}
+	}
+
+	return citations
+}
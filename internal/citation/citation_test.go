@@ -0,0 +1,68 @@
+package citation
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"trpc.group/trpc-go/trpc-a2a-go/protocol"
+)
+
+func TestReferenced(t *testing.T) {
+	citations := NewCitations(nil)
+	for i := 1; i <= 12; i++ {
+		citations.Add(fmt.Sprintf("article-%d", i), fmt.Sprintf("Article %d", i))
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []int
+	}{
+		{name: "markers", text: "Restart the agent [1]. Then check the logs [3].", want: []int{1, 3}},
+		{name: "adjacent markers", text: "Both apply [2][4].", want: []int{2, 4}},
+		{name: "two digits", text: "See [12].", want: []int{12}},
+		{name: "one digit is not a prefix", text: "See [12] only.", want: []int{12}},
+		{name: "start of text", text: "[5] explains it.", want: []int{5}},
+		{name: "index", text: "Use items[1] and array_2[2].", want: nil},
+		{name: "code span", text: "Run `disks[1]` or `echo [2]`, as [3] says.", want: []int{3}},
+		{name: "fenced code", text: "Run:\n```\nfor i in [1] [2]; do echo $i; done\n```\nSee [6].", want: []int{6}},
+		{name: "unclosed fence", text: "See [7].\n```\nvalues = [8]", want: []int{7}},
+		{name: "unknown article", text: "See [99].", want: nil},
+		{name: "not a number", text: "See [a] and [ 1 ].", want: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []int
+			for _, citation := range citations.Referenced(test.text) {
+				got = append(got, citation.Number)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(test.want) {
+				t.Errorf("got citations %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFromParts(t *testing.T) {
+	want := []Citation{{Number: 1, ID: "article-1", Title: "Article 1"}, {Number: 3, ID: "article-3", Title: "Article 3"}}
+	sent := protocol.Artifact{ArtifactID: "answer", Parts: []protocol.Part{
+		protocol.NewTextPart("Restart the agent [1]. Then check the logs [3]."),
+		protocol.NewDataPart(map[string]interface{}{"citations": want}),
+		protocol.NewDataPart(map[string]interface{}{"other": "data"}),
+	}}
+
+	// The artifact is read as a client receives it.
+	data, err := json.Marshal(sent)
+	if err != nil {
+		t.Fatalf("failed to marshal artifact: %v", err)
+	}
+	var received protocol.Artifact
+	if err := json.Unmarshal(data, &received); err != nil {
+		t.Fatalf("failed to unmarshal artifact: %v", err)
+	}
+
+	got := FromParts(received.Parts)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got citations %v, want %v", got, want)
+	}
+}
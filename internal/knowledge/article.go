@@ -0,0 +1,164 @@
+package knowledge
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type Article struct {
+	ID    string   `json:"id"`
+	Title string   `json:"title"`
+	Body  string   `json:"body"`
+	Tags  []string `json:"tags,omitempty"`
+	// Path is the file the article was loaded from, relative to the articles directory.
+	Path string `json:"path,omitempty"`
+}
+
+// LoadArticles reads every Markdown (.md) and JSON (.json) article below dir. A JSON file may hold
+// a single article or an array of articles.
+func LoadArticles(dir string) ([]Article, error) {
+	var articles []Article
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		relative, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		var loaded []Article
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".md", ".markdown":
+			article, err := loadMarkdown(path)
+			if err != nil {
+				return err
+			}
+			loaded = []Article{article}
+		case ".json":
+			loaded, err = loadJSON(path)
+			if err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+
+		for i := range loaded {
+			loaded[i].Path = filepath.ToSlash(relative)
+			if loaded[i].ID == "" {
+				loaded[i].ID = articleID(relative, i, len(loaded))
+			}
+			if loaded[i].Title == "" {
+				loaded[i].Title = loaded[i].ID
+			}
+		}
+		articles = append(articles, loaded...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load knowledge articles: %w", err)
+	}
+
+	seen := map[string]string{}
+	for _, article := range articles {
+		if path, ok := seen[article.ID]; ok {
+			return nil, fmt.Errorf("failed to load knowledge articles: id %q is used by %s and %s", article.ID, path, article.Path)
+		}
+		seen[article.ID] = article.Path
+	}
+
+	sort.Slice(articles, func(i, j int) bool { return articles[i].ID < articles[j].ID })
+	return articles, nil
+}
+
+// loadMarkdown reads a Markdown article. An optional front matter block between "---" lines may set
+// the id, title and tags, otherwise the first level one heading is used as the title.
+func loadMarkdown(path string) (Article, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Article{}, err
+	}
+
+	var article Article
+	body := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	if strings.HasPrefix(body, "---\n") {
+		frontMatter, rest, found := strings.Cut(body[4:], "\n---\n")
+		if !found {
+			return Article{}, fmt.Errorf("%s: front matter is not closed", path)
+		}
+		body = rest
+
+		scanner := bufio.NewScanner(strings.NewReader(frontMatter))
+		for scanner.Scan() {
+			key, value, ok := strings.Cut(scanner.Text(), ":")
+			if !ok {
+				continue
+			}
+			value = strings.Trim(strings.TrimSpace(value), `"`)
+			switch strings.TrimSpace(key) {
+			case "id":
+				article.ID = value
+			case "title":
+				article.Title = value
+			case "tags":
+				for _, tag := range strings.Split(strings.Trim(value, "[]"), ",") {
+					if tag = strings.Trim(strings.TrimSpace(tag), `"`); tag != "" {
+						article.Tags = append(article.Tags, tag)
+					}
+				}
+			}
+		}
+	}
+
+	body = strings.TrimSpace(body)
+	if article.Title == "" && strings.HasPrefix(body, "# ") {
+		heading, rest, _ := strings.Cut(body, "\n")
+		article.Title = strings.TrimSpace(strings.TrimPrefix(heading, "# "))
+		body = strings.TrimSpace(rest)
+	}
+	article.Body = body
+
+	return article, nil
+}
+
+func loadJSON(path string) ([]Article, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var articles []Article
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		err = json.Unmarshal(data, &articles)
+	} else {
+		var article Article
+		err = json.Unmarshal(data, &article)
+		articles = []Article{article}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return articles, nil
+}
+
+func articleID(path string, index int, count int) string {
+	id := strings.TrimSuffix(filepath.ToSlash(path), filepath.Ext(path))
+	if count > 1 {
+		id = fmt.Sprintf("%s-%d", id, index+1)
+	}
+	return id
+}
@@ -0,0 +1,225 @@
+package knowledge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const indexVersion = 1
+
+// BM25 parameters.
+const (
+	k1 = 1.2
+	b  = 0.75
+)
+
+// titleWeight counts every title term this many times so that articles whose title matches the
+// question rank above articles that only mention it.
+const titleWeight = 3
+
+type posting struct {
+	Document  int `json:"d"`
+	Frequency int `json:"f"`
+}
+
+// Index is a BM25 index of knowledge articles. It holds the articles themselves so that a saved index
+// can be searched without the article files.
+type Index struct {
+	Version       int                  `json:"version"`
+	BuiltAt       time.Time            `json:"builtAt"`
+	Articles      []Article            `json:"articles"`
+	Lengths       []int                `json:"lengths"`
+	AverageLength float64              `json:"averageLength"`
+	Postings      map[string][]posting `json:"postings"`
+}
+
+type Result struct {
+	ID      string  `json:"id"`
+	Title   string  `json:"title"`
+	Score   float64 `json:"score"`
+	Snippet string  `json:"snippet"`
+	Article Article `json:"-"`
+}
+
+func BuildIndex(articles []Article) *Index {
+	index := &Index{
+		Version:  indexVersion,
+		BuiltAt:  time.Now().UTC(),
+		Articles: articles,
+		Lengths:  make([]int, len(articles)),
+		Postings: map[string][]posting{},
+	}
+
+	total := 0
+	for i, article := range articles {
+		frequencies := map[string]int{}
+		for _, term := range tokenize(article.Title) {
+			frequencies[term] += titleWeight
+		}
+		for _, term := range tokenize(strings.Join(article.Tags, " ")) {
+			frequencies[term] += titleWeight
+		}
+		for _, term := range tokenize(article.Body) {
+			frequencies[term]++
+		}
+
+		length := 0
+		for term, frequency := range frequencies {
+			index.Postings[term] = append(index.Postings[term], posting{Document: i, Frequency: frequency})
+			length += frequency
+		}
+		index.Lengths[i] = length
+		total += length
+	}
+
+	if len(articles) > 0 {
+		index.AverageLength = float64(total) / float64(len(articles))
+	}
+
+	return index
+}
+
+// BuildIndexFromDir loads the articles below dir and indexes them.
+func BuildIndexFromDir(dir string) (*Index, error) {
+	articles, err := LoadArticles(dir)
+	if err != nil {
+		return nil, err
+	}
+	return BuildIndex(articles), nil
+}
+
+// OpenIndex loads the index saved at path. When there is no saved index one is built from the
+// articles below dir and saved to path.
+func OpenIndex(path string, dir string) (*Index, error) {
+	index, err := LoadIndex(path)
+	if err == nil {
+		return index, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	index, err = BuildIndexFromDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := index.Save(path); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func LoadIndex(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read knowledge index: %w", err)
+	}
+
+	var index Index
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("failed to read knowledge index %s: %w", path, err)
+	}
+	if index.Version != indexVersion {
+		return nil, fmt.Errorf("knowledge index %s has version %d, expected %d. rebuild it", path, index.Version, indexVersion)
+	}
+
+	return &index, nil
+}
+
+// Save writes the index to path, replacing any previous index atomically.
+func (i *Index) Save(path string) error {
+	data, err := json.Marshal(i)
+	if err != nil {
+		return fmt.Errorf("failed to encode knowledge index: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to write knowledge index: %w", err)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), ".knowledge-index-*")
+	if err != nil {
+		return fmt.Errorf("failed to write knowledge index: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write knowledge index: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write knowledge index: %w", err)
+	}
+
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write knowledge index: %w", err)
+	}
+	return nil
+}
+
+// Search returns up to limit articles ranked by their BM25 score for the question. Articles that
+// share no terms with the question are not returned.
+func (i *Index) Search(question string, limit int) []Result {
+	terms := uniqueTerms(tokenize(question))
+	scores := map[int]float64{}
+
+	for _, term := range terms {
+		postings := i.Postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+
+		documents := float64(len(i.Articles))
+		idf := math.Log(1 + (documents-float64(len(postings))+0.5)/(float64(len(postings))+0.5))
+
+		for _, posting := range postings {
+			frequency := float64(posting.Frequency)
+			norm := 1 - b + b*float64(i.Lengths[posting.Document])/i.AverageLength
+			scores[posting.Document] += idf * frequency * (k1 + 1) / (frequency + k1*norm)
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for document, score := range scores {
+		article := i.Articles[document]
+		results = append(results, Result{
+			ID:      article.ID,
+			Title:   article.Title,
+			Score:   math.Round(score*1000) / 1000,
+			Snippet: snippet(article.Body, terms),
+			Article: article,
+		})
+	}
+
+	sort.Slice(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		return results[a].ID < results[b].ID
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := map[string]bool{}
+	unique := terms[:0]
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
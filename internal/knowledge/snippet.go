@@ -0,0 +1,56 @@
+package knowledge
+
+import (
+	"strings"
+)
+
+const snippetLength = 300
+
+// snippet returns the passage of the body that mentions the most distinct question terms, together
+// with the passages that follow it up to the snippet length.
+func snippet(body string, terms []string) string {
+	var passages []string
+	for _, line := range strings.Split(body, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			passages = append(passages, line)
+		}
+	}
+	if len(passages) == 0 {
+		return ""
+	}
+
+	wanted := map[string]bool{}
+	for _, term := range terms {
+		wanted[term] = true
+	}
+
+	best, bestMatches := 0, 0
+	for i, passage := range passages {
+		matches := map[string]bool{}
+		for _, term := range tokenize(passage) {
+			if wanted[term] {
+				matches[term] = true
+			}
+		}
+		if len(matches) > bestMatches {
+			best, bestMatches = i, len(matches)
+		}
+	}
+
+	text := passages[best]
+	for _, passage := range passages[best+1:] {
+		if len(text) >= snippetLength {
+			break
+		}
+		text += " " + passage
+	}
+
+	if runes := []rune(text); len(runes) > snippetLength {
+		text = string(runes[:snippetLength])
+		if space := strings.LastIndex(text, " "); space > snippetLength/2 {
+			text = text[:space]
+		}
+		text += "..."
+	}
+	return text
+}
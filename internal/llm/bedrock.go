@@ -0,0 +1,211 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+)
+
+type BedrockModel struct {
+	Client  *bedrockruntime.Client
+	ModelID string
+}
+
+// NewBedrockModel creates a model that uses the Bedrock Converse API with the credentials of the
+// given shared config profile. An empty profile uses the default credential chain.
+func NewBedrockModel(ctx context.Context, region string, profile string, modelID string) (*BedrockModel, error) {
+	options := []func(*config.LoadOptions) error{config.WithRegion(region)}
+	if profile != "" {
+		options = append(options, config.WithSharedConfigProfile(profile))
+	}
+
+	awsConfig, err := config.LoadDefaultConfig(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load AWS config: %w", err)
+	}
+
+	return &BedrockModel{
+		Client:  bedrockruntime.NewFromConfig(awsConfig),
+		ModelID: modelID,
+	}, nil
+}
+
+func (m *BedrockModel) Chat(ctx context.Context, request *Request) (*Response, error) {
+	input := m.converseInput(request)
+
+	output, err := m.Client.Converse(ctx, &bedrockruntime.ConverseInput{
+		ModelId:         input.ModelId,
+		Messages:        input.Messages,
+		System:          input.System,
+		ToolConfig:      input.ToolConfig,
+		InferenceConfig: input.InferenceConfig,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	message, ok := output.Output.(*types.ConverseOutputMemberMessage)
+	if !ok {
+		return nil, fmt.Errorf("unexpected converse output type %T", output.Output)
+	}
+
+	response := &Response{
+		Message:    fromBedrockMessage(message.Value),
+		StopReason: StopReason(output.StopReason),
+	}
+	if output.Usage != nil {
+		response.Usage = fromBedrockUsage(output.Usage)
+	}
+	return response, nil
+}
+
+func (m *BedrockModel) ChatStream(ctx context.Context, request *Request, onText func(text string) error) (*Response, error) {
+	streamOutput, err := m.Client.ConverseStream(ctx, m.converseInput(request))
+	if err != nil {
+		return nil, err
+	}
+
+	stream := streamOutput.GetStream()
+	defer stream.Close()
+
+	assembler := newStreamAssembler()
+	for event := range stream.Events() {
+		if text := assembler.add(event); text != "" {
+			if err := onText(text); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+
+	return assembler.response()
+}
+
+func (m *BedrockModel) converseInput(request *Request) *bedrockruntime.ConverseStreamInput {
+	modelID := m.ModelID
+	if request.Model != "" {
+		modelID = request.Model
+	}
+
+	input := &bedrockruntime.ConverseStreamInput{
+		ModelId:         aws.String(modelID),
+		Messages:        toBedrockMessages(request.Messages),
+		InferenceConfig: &types.InferenceConfiguration{Temperature: request.Temperature},
+	}
+	if request.MaxTokens > 0 {
+		input.InferenceConfig.MaxTokens = aws.Int32(int32(request.MaxTokens))
+	}
+	if request.System != "" {
+		input.System = []types.SystemContentBlock{&types.SystemContentBlockMemberText{Value: request.System}}
+	}
+	if len(request.Tools) > 0 {
+		tools := make([]types.Tool, 0, len(request.Tools))
+		for _, tool := range request.Tools {
+			tools = append(tools, &types.ToolMemberToolSpec{
+				Value: types.ToolSpecification{
+					Name:        aws.String(tool.Name),
+					Description: aws.String(tool.Description),
+					InputSchema: &types.ToolInputSchemaMemberJson{Value: document.NewLazyDocument(tool.InputSchema)},
+				},
+			})
+		}
+		input.ToolConfig = &types.ToolConfiguration{Tools: tools}
+	}
+
+	return input
+}
+
+func toBedrockMessages(messages []Message) []types.Message {
+	converted := make([]types.Message, 0, len(messages))
+	for _, message := range messages {
+		content := make([]types.ContentBlock, 0, len(message.Content))
+		for _, block := range message.Content {
+			switch b := block.(type) {
+			case *TextBlock:
+				content = append(content, &types.ContentBlockMemberText{Value: b.Text})
+			case *ToolUseBlock:
+				content = append(content, &types.ContentBlockMemberToolUse{
+					Value: types.ToolUseBlock{
+						ToolUseId: aws.String(b.ID),
+						Name:      aws.String(b.Name),
+						Input:     document.NewLazyDocument(b.Input),
+					},
+				})
+			case *ToolResultBlock:
+				result := types.ToolResultBlock{ToolUseId: aws.String(b.ToolUseID)}
+				if b.JSON != nil {
+					result.Content = []types.ToolResultContentBlock{&types.ToolResultContentBlockMemberJson{Value: document.NewLazyDocument(b.JSON)}}
+				} else {
+					result.Content = []types.ToolResultContentBlock{&types.ToolResultContentBlockMemberText{Value: b.Text}}
+				}
+				if b.IsError {
+					result.Status = types.ToolResultStatusError
+				}
+				content = append(content, &types.ContentBlockMemberToolResult{Value: result})
+			case *ReasoningBlock:
+				if b.Redacted != nil {
+					content = append(content, &types.ContentBlockMemberReasoningContent{
+						Value: &types.ReasoningContentBlockMemberRedactedContent{Value: b.Redacted},
+					})
+					continue
+				}
+				reasoning := types.ReasoningTextBlock{Text: aws.String(b.Text)}
+				if b.Signature != "" {
+					reasoning.Signature = aws.String(b.Signature)
+				}
+				content = append(content, &types.ContentBlockMemberReasoningContent{
+					Value: &types.ReasoningContentBlockMemberReasoningText{Value: reasoning},
+				})
+			}
+		}
+		converted = append(converted, types.Message{Role: types.ConversationRole(message.Role), Content: content})
+	}
+	return converted
+}
+
+func fromBedrockMessage(message types.Message) Message {
+	converted := Message{Role: Role(message.Role)}
+	for _, block := range message.Content {
+		switch b := block.(type) {
+		case *types.ContentBlockMemberText:
+			converted.Content = append(converted.Content, &TextBlock{Text: b.Value})
+		case *types.ContentBlockMemberToolUse:
+			input := map[string]interface{}{}
+			if b.Value.Input != nil {
+				if err := b.Value.Input.UnmarshalSmithyDocument(&input); err != nil {
+					fmt.Printf("Ignoring tool input that could not be read: %v\n", err)
+				}
+			}
+			converted.Content = append(converted.Content, &ToolUseBlock{
+				ID:    aws.ToString(b.Value.ToolUseId),
+				Name:  aws.ToString(b.Value.Name),
+				Input: input,
+			})
+		case *types.ContentBlockMemberReasoningContent:
+			switch reasoning := b.Value.(type) {
+			case *types.ReasoningContentBlockMemberReasoningText:
+				converted.Content = append(converted.Content, &ReasoningBlock{
+					Text:      aws.ToString(reasoning.Value.Text),
+					Signature: aws.ToString(reasoning.Value.Signature),
+				})
+			case *types.ReasoningContentBlockMemberRedactedContent:
+				converted.Content = append(converted.Content, &ReasoningBlock{Redacted: reasoning.Value})
+			}
+		}
+	}
+	return converted
+}
+
+func fromBedrockUsage(usage *types.TokenUsage) Usage {
+	return Usage{
+		InputTokens:  int(aws.ToInt32(usage.InputTokens)),
+		OutputTokens: int(aws.ToInt32(usage.OutputTokens)),
+		TotalTokens:  int(aws.ToInt32(usage.TotalTokens)),
+	}
+}
@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"sort"
+	"strings"
+)
+
+// streamBlock is a content block of a streamed turn that is still being received.
+type streamBlock struct {
+	text      strings.Builder
+	toolUse   *types.ToolUseBlockStart
+	toolInput strings.Builder
+	reasoning bool
+	signature string
+	redacted  []byte
+}
+
+// streamAssembler rebuilds the message of a model turn from ConverseStream events.
+type streamAssembler struct {
+	role       types.ConversationRole
+	blocks     map[int32]*streamBlock
+	stopReason types.StopReason
+	usage      *types.TokenUsage
+	// textStarted is set once answer text has been returned, after which a new text block is
+	// separated from the text before it as in Message.Text.
+	textStarted bool
+}
+
+func newStreamAssembler() *streamAssembler {
+	return &streamAssembler{
+		role:   types.ConversationRoleAssistant,
+		blocks: map[int32]*streamBlock{},
+	}
+}
+
+func (a *streamAssembler) block(index *int32) *streamBlock {
+	key := aws.ToInt32(index)
+	block, ok := a.blocks[key]
+	if !ok {
+		block = &streamBlock{}
+		a.blocks[key] = block
+	}
+	return block
+}
+
+// add applies an event and returns the text it adds to the answer, if any.
+func (a *streamAssembler) add(event types.ConverseStreamOutput) string {
+	switch e := event.(type) {
+	case *types.ConverseStreamOutputMemberMessageStart:
+		a.role = e.Value.Role
+
+	case *types.ConverseStreamOutputMemberContentBlockStart:
+		if start, ok := e.Value.Start.(*types.ContentBlockStartMemberToolUse); ok {
+			a.block(e.Value.ContentBlockIndex).toolUse = &start.Value
+		}
+
+	case *types.ConverseStreamOutputMemberContentBlockDelta:
+		block := a.block(e.Value.ContentBlockIndex)
+		switch delta := e.Value.Delta.(type) {
+		case *types.ContentBlockDeltaMemberText:
+			if delta.Value == "" {
+				return ""
+			}
+			text := delta.Value
+			if a.textStarted && block.text.Len() == 0 {
+				text = "\n\n" + text
+			}
+			a.textStarted = true
+			block.text.WriteString(delta.Value)
+			return text
+		case *types.ContentBlockDeltaMemberToolUse:
+			block.toolInput.WriteString(aws.ToString(delta.Value.Input))
+		case *types.ContentBlockDeltaMemberReasoningContent:
+			block.reasoning = true
+			switch reasoning := delta.Value.(type) {
+			case *types.ReasoningContentBlockDeltaMemberText:
+				block.text.WriteString(reasoning.Value)
+			case *types.ReasoningContentBlockDeltaMemberSignature:
+				block.signature += reasoning.Value
+			case *types.ReasoningContentBlockDeltaMemberRedactedContent:
+				block.redacted = append(block.redacted, reasoning.Value...)
+			}
+		}
+
+	case *types.ConverseStreamOutputMemberMessageStop:
+		a.stopReason = e.Value.StopReason
+
+	case *types.ConverseStreamOutputMemberMetadata:
+		a.usage = e.Value.Usage
+	}
+
+	return ""
+}
+
+// response returns the assembled turn.
+func (a *streamAssembler) response() (*Response, error) {
+	indexes := make([]int32, 0, len(a.blocks))
+	for index := range a.blocks {
+		indexes = append(indexes, index)
+	}
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i] < indexes[j] })
+
+	message := Message{Role: Role(a.role)}
+	for _, index := range indexes {
+		block := a.blocks[index]
+		switch {
+		case block.toolUse != nil:
+			input := map[string]interface{}{}
+			if raw := block.toolInput.String(); raw != "" {
+				if err := json.Unmarshal([]byte(raw), &input); err != nil {
+					// A turn cut off by max_tokens ends in the middle of the tool input. The tool use
+					// cannot be made, so it is dropped and the turn ends with the blocks before it.
+					if a.stopReason == types.StopReasonMaxTokens {
+						fmt.Printf("Ignoring tool use %s cut off by max_tokens\n", aws.ToString(block.toolUse.Name))
+						continue
+					}
+					return nil, fmt.Errorf("streamed tool input for %s is not valid JSON: %w", aws.ToString(block.toolUse.Name), err)
+				}
+			}
+			message.Content = append(message.Content, &ToolUseBlock{
+				ID:    aws.ToString(block.toolUse.ToolUseId),
+				Name:  aws.ToString(block.toolUse.Name),
+				Input: input,
+			})
+
+		case block.redacted != nil:
+			message.Content = append(message.Content, &ReasoningBlock{Redacted: block.redacted})
+
+		case block.reasoning:
+			message.Content = append(message.Content, &ReasoningBlock{Text: block.text.String(), Signature: block.signature})
+
+		default:
+			message.Content = append(message.Content, &TextBlock{Text: block.text.String()})
+		}
+	}
+
+	response := &Response{
+		Message:    message,
+		StopReason: StopReason(a.stopReason),
+	}
+	if a.usage != nil {
+		response.Usage = fromBedrockUsage(a.usage)
+	}
+	return response, nil
+}
@@ -0,0 +1,68 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Config selects the model provider and the inference parameters used for every request.
+type Config struct {
+	// Provider is bedrock or openai for an OpenAI compatible server.
+	Provider string `json:"provider"`
+	// Model is the model used when a request does not pick one.
+	Model   string `json:"model"`
+	Region  string `json:"region"`
+	Profile string `json:"profile"`
+	// Endpoint is the base URL of the OpenAI compatible server.
+	Endpoint    string  `json:"endpoint"`
+	Temperature float32 `json:"temperature"`
+	MaxTokens   int     `json:"maxTokens"`
+	// Models maps the names a request may use to pick a model to model IDs, e.g. fast to a cheaper
+	// model for simple lookups. Requests cannot use models that are not listed.
+	Models map[string]string `json:"models"`
+}
+
+const DefaultBedrockModel = "anthropic.claude-3-haiku-20240307-v1:0"
+
+func DefaultConfig() Config {
+	return Config{
+		Provider:    "bedrock",
+		Region:      "eu-west-1",
+		Profile:     "archpoc_dev-developer",
+		Endpoint:    "http://localhost:8000/v1",
+		Temperature: 0,
+		MaxTokens:   4096,
+	}
+}
+
+// NewModel creates the model for the configured provider. The OpenAI API key is read from
+// OPENAI_API_KEY so that it does not appear in config files or the process list.
+func NewModel(ctx context.Context, config Config) (ChatModel, error) {
+	switch config.Provider {
+	case "bedrock":
+		model := config.Model
+		if model == "" {
+			model = DefaultBedrockModel
+		}
+		return NewBedrockModel(ctx, config.Region, config.Profile, model)
+	case "openai":
+		return NewOpenAIModel(config.Endpoint, os.Getenv("OPENAI_API_KEY"), config.Model), nil
+	default:
+		return nil, fmt.Errorf("unknown model provider %q", config.Provider)
+	}
+}
+
+// ResolveModel returns the model ID for a model requested by name or ID. Only the configured
+// models can be requested.
+func (c Config) ResolveModel(name string) (string, error) {
+	if modelID, ok := c.Models[name]; ok {
+		return modelID, nil
+	}
+	for _, modelID := range c.Models {
+		if modelID == name {
+			return modelID, nil
+		}
+	}
+	return "", fmt.Errorf("model %q is not available", name)
+}
@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// messageJSON is the stored form of a Message. The content blocks are told apart by their type.
+type messageJSON struct {
+	Role    Role        `json:"role"`
+	Content []blockJSON `json:"content"`
+}
+
+type blockJSON struct {
+	Type      string                 `json:"type"`
+	Text      string                 `json:"text,omitempty"`
+	ID        string                 `json:"id,omitempty"`
+	Name      string                 `json:"name,omitempty"`
+	Input     map[string]interface{} `json:"input,omitempty"`
+	ToolUseID string                 `json:"toolUseId,omitempty"`
+	JSON      interface{}            `json:"json,omitempty"`
+	IsError   bool                   `json:"isError,omitempty"`
+	Signature string                 `json:"signature,omitempty"`
+	Redacted  []byte                 `json:"redacted,omitempty"`
+}
+
+func (m Message) MarshalJSON() ([]byte, error) {
+	stored := messageJSON{Role: m.Role, Content: make([]blockJSON, 0, len(m.Content))}
+	for _, block := range m.Content {
+		switch b := block.(type) {
+		case *TextBlock:
+			stored.Content = append(stored.Content, blockJSON{Type: "text", Text: b.Text})
+		case *ToolUseBlock:
+			stored.Content = append(stored.Content, blockJSON{Type: "toolUse", ID: b.ID, Name: b.Name, Input: b.Input})
+		case *ToolResultBlock:
+			stored.Content = append(stored.Content, blockJSON{Type: "toolResult", ToolUseID: b.ToolUseID, Text: b.Text, JSON: b.JSON, IsError: b.IsError})
+		case *ReasoningBlock:
+			stored.Content = append(stored.Content, blockJSON{Type: "reasoning", Text: b.Text, Signature: b.Signature, Redacted: b.Redacted})
+		default:
+			return nil, fmt.Errorf("unsupported content block %T", block)
+		}
+	}
+	return json.Marshal(stored)
+}
+
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var stored messageJSON
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return err
+	}
+
+	m.Role = stored.Role
+	m.Content = make([]ContentBlock, 0, len(stored.Content))
+	for _, b := range stored.Content {
+		switch b.Type {
+		case "text":
+			m.Content = append(m.Content, &TextBlock{Text: b.Text})
+		case "toolUse":
+			m.Content = append(m.Content, &ToolUseBlock{ID: b.ID, Name: b.Name, Input: b.Input})
+		case "toolResult":
+			m.Content = append(m.Content, &ToolResultBlock{ToolUseID: b.ToolUseID, Text: b.Text, JSON: b.JSON, IsError: b.IsError})
+		case "reasoning":
+			m.Content = append(m.Content, &ReasoningBlock{Text: b.Text, Signature: b.Signature, Redacted: b.Redacted})
+		default:
+			return fmt.Errorf("unsupported content block type %q", b.Type)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// FakeModel is a scripted ChatModel for tests. Every call returns the next response in order and
+// the requests it was given are recorded.
+type FakeModel struct {
+	mu        sync.Mutex
+	responses []*Response
+	Requests  []*Request
+}
+
+func NewFakeModel(responses ...*Response) *FakeModel {
+	return &FakeModel{responses: responses}
+}
+
+func (m *FakeModel) Chat(ctx context.Context, request *Request) (*Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	copied := *request
+	copied.Messages = append([]Message(nil), request.Messages...)
+	m.Requests = append(m.Requests, &copied)
+
+	if len(m.responses) == 0 {
+		return nil, errors.New("fake model has no scripted responses left")
+	}
+	response := m.responses[0]
+	m.responses = m.responses[1:]
+	return response, nil
+}
+
+func (m *FakeModel) ChatStream(ctx context.Context, request *Request, onText func(text string) error) (*Response, error) {
+	response, err := m.Chat(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	separator := ""
+	for _, block := range response.Message.Content {
+		if text, ok := block.(*TextBlock); ok && text.Text != "" {
+			if err := onText(separator + text.Text); err != nil {
+				return nil, err
+			}
+			separator = "\n\n"
+		}
+	}
+	return response, nil
+}
+
+// TextResponse returns a response that ends the turn with the given text.
+func TextResponse(text string) *Response {
+	return &Response{
+		Message:    Message{Role: RoleAssistant, Content: []ContentBlock{&TextBlock{Text: text}}},
+		StopReason: StopReasonEndTurn,
+	}
+}
+
+// ToolUseResponse returns a response that asks for one tool call.
+func ToolUseResponse(id string, name string, input map[string]interface{}) *Response {
+	return &Response{
+		Message:    Message{Role: RoleAssistant, Content: []ContentBlock{&ToolUseBlock{ID: id, Name: name, Input: input}}},
+		StopReason: StopReasonToolUse,
+	}
+}
@@ -0,0 +1,380 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// OpenAIModel uses the chat completions API of an OpenAI compatible server, such as a local
+// llama.cpp or vLLM server.
+type OpenAIModel struct {
+	// BaseURL is the URL the API paths are relative to, e.g. http://localhost:8000/v1.
+	BaseURL    string
+	APIKey     string
+	Model      string
+	HTTPClient *http.Client
+}
+
+func NewOpenAIModel(baseURL string, apiKey string, model string) *OpenAIModel {
+	return &OpenAIModel{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		APIKey:     apiKey,
+		Model:      model,
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    *string          `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	Index    *int   `json:"index,omitempty"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAITool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string                 `json:"name"`
+		Description string                 `json:"description,omitempty"`
+		Parameters  map[string]interface{} `json:"parameters"`
+	} `json:"function"`
+}
+
+type openAIRequest struct {
+	Model         string                 `json:"model"`
+	Messages      []openAIMessage        `json:"messages"`
+	Tools         []openAITool           `json:"tools,omitempty"`
+	Temperature   *float32               `json:"temperature,omitempty"`
+	MaxTokens     int                    `json:"max_tokens,omitempty"`
+	Stream        bool                   `json:"stream,omitempty"`
+	StreamOptions map[string]interface{} `json:"stream_options,omitempty"`
+}
+
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message      openAIMessage `json:"message"`
+		Delta        openAIMessage `json:"delta"`
+		FinishReason *string       `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+func (m *OpenAIModel) Chat(ctx context.Context, request *Request) (*Response, error) {
+	body, err := m.post(ctx, m.chatRequest(request, false))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var completion openAIResponse
+	if err := json.NewDecoder(body).Decode(&completion); err != nil {
+		return nil, fmt.Errorf("chat completion response is not valid: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("chat completion response has no choices")
+	}
+
+	choice := completion.Choices[0]
+	message := Message{Role: RoleAssistant}
+	if choice.Message.Content != nil && *choice.Message.Content != "" {
+		message.Content = append(message.Content, &TextBlock{Text: *choice.Message.Content})
+	}
+	for _, toolCall := range choice.Message.ToolCalls {
+		toolUse, err := fromOpenAIToolCall(toolCall.ID, toolCall.Function.Name, toolCall.Function.Arguments)
+		if err != nil {
+			if cutOff(choice.FinishReason) {
+				fmt.Printf("Ignoring tool call %s cut off by the length limit\n", toolCall.Function.Name)
+				continue
+			}
+			return nil, err
+		}
+		message.Content = append(message.Content, toolUse)
+	}
+
+	return &Response{
+		Message:    message,
+		StopReason: fromOpenAIFinishReason(choice.FinishReason, message),
+		Usage:      fromOpenAIUsage(completion.Usage),
+	}, nil
+}
+
+func (m *OpenAIModel) ChatStream(ctx context.Context, request *Request, onText func(text string) error) (*Response, error) {
+	body, err := m.post(ctx, m.chatRequest(request, true))
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	type pendingCall struct {
+		id        string
+		name      string
+		arguments strings.Builder
+	}
+
+	var text strings.Builder
+	calls := map[int]*pendingCall{}
+	var finishReason *string
+	var usage *openAIUsage
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("chat completion chunk is not valid: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.FinishReason != nil {
+			finishReason = choice.FinishReason
+		}
+		if delta := choice.Delta.Content; delta != nil && *delta != "" {
+			text.WriteString(*delta)
+			if err := onText(*delta); err != nil {
+				return nil, err
+			}
+		}
+		for i, toolCall := range choice.Delta.ToolCalls {
+			index := i
+			if toolCall.Index != nil {
+				index = *toolCall.Index
+			}
+			call, ok := calls[index]
+			if !ok {
+				call = &pendingCall{}
+				calls[index] = call
+			}
+			if toolCall.ID != "" {
+				call.id = toolCall.ID
+			}
+			if toolCall.Function.Name != "" {
+				call.name = toolCall.Function.Name
+			}
+			call.arguments.WriteString(toolCall.Function.Arguments)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("chat completion stream failed: %w", err)
+	}
+
+	message := Message{Role: RoleAssistant}
+	if text.Len() > 0 {
+		message.Content = append(message.Content, &TextBlock{Text: text.String()})
+	}
+
+	indexes := make([]int, 0, len(calls))
+	for index := range calls {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		call := calls[index]
+		toolUse, err := fromOpenAIToolCall(call.id, call.name, call.arguments.String())
+		if err != nil {
+			if cutOff(finishReason) {
+				fmt.Printf("Ignoring tool call %s cut off by the length limit\n", call.name)
+				continue
+			}
+			return nil, err
+		}
+		message.Content = append(message.Content, toolUse)
+	}
+
+	return &Response{
+		Message:    message,
+		StopReason: fromOpenAIFinishReason(finishReason, message),
+		Usage:      fromOpenAIUsage(usage),
+	}, nil
+}
+
+func (m *OpenAIModel) post(ctx context.Context, request *openAIRequest) (io.ReadCloser, error) {
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal chat completion request: %w", err)
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, m.BaseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create chat completion request: %w", err)
+	}
+	httpRequest.Header.Set("Content-Type", "application/json")
+	if m.APIKey != "" {
+		httpRequest.Header.Set("Authorization", "Bearer "+m.APIKey)
+	}
+
+	httpResponse, err := m.HTTPClient.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("chat completion request failed: %w", err)
+	}
+	if httpResponse.StatusCode < 200 || httpResponse.StatusCode > 299 {
+		defer httpResponse.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(httpResponse.Body, 2000))
+		return nil, fmt.Errorf("chat completion failed with HTTP status %s: %s", httpResponse.Status, body)
+	}
+
+	return httpResponse.Body, nil
+}
+
+func (m *OpenAIModel) chatRequest(request *Request, stream bool) *openAIRequest {
+	model := m.Model
+	if request.Model != "" {
+		model = request.Model
+	}
+
+	chatRequest := &openAIRequest{
+		Model:       model,
+		Temperature: request.Temperature,
+		MaxTokens:   request.MaxTokens,
+		Stream:      stream,
+	}
+	if stream {
+		chatRequest.StreamOptions = map[string]interface{}{"include_usage": true}
+	}
+
+	if request.System != "" {
+		chatRequest.Messages = append(chatRequest.Messages, openAIMessage{Role: "system", Content: StringPtr(request.System)})
+	}
+	chatRequest.Messages = append(chatRequest.Messages, toOpenAIMessages(request.Messages)...)
+
+	for _, spec := range request.Tools {
+		var tool openAITool
+		tool.Type = "function"
+		tool.Function.Name = spec.Name
+		tool.Function.Description = spec.Description
+		tool.Function.Parameters = spec.InputSchema
+		chatRequest.Tools = append(chatRequest.Tools, tool)
+	}
+
+	return chatRequest
+}
+
+// toOpenAIMessages converts the conversation. Tool results become messages with the tool role and
+// reasoning is dropped, as chat completions has no way to send it back.
+func toOpenAIMessages(messages []Message) []openAIMessage {
+	var converted []openAIMessage
+	for _, message := range messages {
+		var texts []string
+		var toolCalls []openAIToolCall
+
+		for _, block := range message.Content {
+			switch b := block.(type) {
+			case *TextBlock:
+				texts = append(texts, b.Text)
+			case *ToolUseBlock:
+				arguments, err := json.Marshal(b.Input)
+				if err != nil {
+					arguments = []byte("{}")
+				}
+				var toolCall openAIToolCall
+				toolCall.ID = b.ID
+				toolCall.Type = "function"
+				toolCall.Function.Name = b.Name
+				toolCall.Function.Arguments = string(arguments)
+				toolCalls = append(toolCalls, toolCall)
+			case *ToolResultBlock:
+				content := b.ResultText()
+				if b.IsError {
+					content = "Error: " + content
+				}
+				converted = append(converted, openAIMessage{Role: "tool", ToolCallID: b.ToolUseID, Content: StringPtr(content)})
+			}
+		}
+
+		if len(texts) == 0 && len(toolCalls) == 0 {
+			continue
+		}
+		openAIMessage := openAIMessage{Role: string(message.Role), ToolCalls: toolCalls}
+		if len(texts) > 0 {
+			openAIMessage.Content = StringPtr(strings.Join(texts, "\n\n"))
+		}
+		converted = append(converted, openAIMessage)
+	}
+	return converted
+}
+
+func fromOpenAIToolCall(id string, name string, arguments string) (*ToolUseBlock, error) {
+	input := map[string]interface{}{}
+	if strings.TrimSpace(arguments) != "" {
+		if err := json.Unmarshal([]byte(arguments), &input); err != nil {
+			return nil, fmt.Errorf("tool call arguments for %s are not valid JSON: %w", name, err)
+		}
+	}
+	return &ToolUseBlock{ID: id, Name: name, Input: input}, nil
+}
+
+// cutOff reports whether a turn ended at the length limit, which leaves the arguments of its last
+// tool call incomplete.
+func cutOff(finishReason *string) bool {
+	return finishReason != nil && *finishReason == "length"
+}
+
+func fromOpenAIFinishReason(finishReason *string, message Message) StopReason {
+	reason := ""
+	if finishReason != nil {
+		reason = *finishReason
+	}
+
+	switch reason {
+	case "tool_calls", "function_call":
+		return StopReasonToolUse
+	case "length":
+		return StopReasonMaxTokens
+	case "content_filter":
+		return StopReasonContentFiltered
+	default:
+		// Some servers report "stop" even when the turn ends with tool calls.
+		if len(message.ToolUses()) > 0 {
+			return StopReasonToolUse
+		}
+		return StopReasonEndTurn
+	}
+}
+
+func fromOpenAIUsage(usage *openAIUsage) Usage {
+	if usage == nil {
+		return Usage{}
+	}
+	return Usage{
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+		TotalTokens:  usage.TotalTokens,
+	}
+}
@@ -0,0 +1,141 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleAssistant Role = "assistant"
+)
+
+type StopReason string
+
+const (
+	StopReasonEndTurn             StopReason = "end_turn"
+	StopReasonToolUse             StopReason = "tool_use"
+	StopReasonMaxTokens           StopReason = "max_tokens"
+	StopReasonStopSequence        StopReason = "stop_sequence"
+	StopReasonContentFiltered     StopReason = "content_filtered"
+	StopReasonGuardrailIntervened StopReason = "guardrail_intervened"
+)
+
+type Message struct {
+	Role    Role
+	Content []ContentBlock
+}
+
+// ContentBlock is one of TextBlock, ToolUseBlock, ToolResultBlock or ReasoningBlock.
+type ContentBlock interface {
+	isContentBlock()
+}
+
+type TextBlock struct {
+	Text string
+}
+
+type ToolUseBlock struct {
+	ID    string
+	Name  string
+	Input map[string]interface{}
+}
+
+type ToolResultBlock struct {
+	ToolUseID string
+	// Text or JSON holds the result. Providers without structured tool results receive JSON as text.
+	Text    string
+	JSON    interface{}
+	IsError bool
+}
+
+// ReasoningBlock holds the model's reasoning. It must be sent back unchanged with the rest of the
+// turn, so the signature and redacted content are kept.
+type ReasoningBlock struct {
+	Text      string
+	Signature string
+	Redacted  []byte
+}
+
+func (*TextBlock) isContentBlock()       {}
+func (*ToolUseBlock) isContentBlock()    {}
+func (*ToolResultBlock) isContentBlock() {}
+func (*ReasoningBlock) isContentBlock()  {}
+
+// ResultText returns the result as text, serialising a JSON result.
+func (b *ToolResultBlock) ResultText() string {
+	if b.JSON == nil {
+		return b.Text
+	}
+	data, err := json.Marshal(b.JSON)
+	if err != nil {
+		return b.Text
+	}
+	return string(data)
+}
+
+// Text joins the text blocks of the message.
+func (m Message) Text() string {
+	var texts []string
+	for _, block := range m.Content {
+		if text, ok := block.(*TextBlock); ok && text.Text != "" {
+			texts = append(texts, text.Text)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+func (m Message) ToolUses() []*ToolUseBlock {
+	var toolUses []*ToolUseBlock
+	for _, block := range m.Content {
+		if toolUse, ok := block.(*ToolUseBlock); ok {
+			toolUses = append(toolUses, toolUse)
+		}
+	}
+	return toolUses
+}
+
+type ToolSpec struct {
+	Name        string
+	Description string
+	// InputSchema is the JSON schema of the tool input.
+	InputSchema map[string]interface{}
+}
+
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+	TotalTokens  int
+}
+
+type Request struct {
+	// Model overrides the model the ChatModel was created for.
+	Model       string
+	System      string
+	Messages    []Message
+	Tools       []ToolSpec
+	Temperature *float32
+	MaxTokens   int
+}
+
+type Response struct {
+	Message    Message
+	StopReason StopReason
+	Usage      Usage
+}
+
+// ChatModel is a model that takes part in a conversation and can use tools.
+type ChatModel interface {
+	Chat(ctx context.Context, request *Request) (*Response, error)
+	// ChatStream works as Chat and also passes the text of the answer to onText as it is generated.
+	// The text passed matches Message.Text of the response, so text blocks are separated by a blank line.
+	ChatStream(ctx context.Context, request *Request, onText func(text string) error) (*Response, error)
+}
+
+// StringPtr returns a pointer to a copy of s, for the optional string fields of API types.
+func StringPtr(s string) *string {
+	return &s
+}
@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"fusion/graph"
 	"fusion/graph/model"
-	"github.com/google/uuid"
+	"fusion/internal/citation"
 	"trpc.group/trpc-go/trpc-a2a-go/client"
 	"trpc.group/trpc-go/trpc-a2a-go/protocol"
 )
@@ -15,9 +15,14 @@ type Resolver struct {
 	a2aClient *client.A2AClient
 }
 
+type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
 type subscriptionResolver struct{ *Resolver }
 
+func (r *Resolver) Mutation() graph.MutationResolver {
+	return &mutationResolver{r}
+}
+
 func (r *Resolver) Query() graph.QueryResolver {
 	return &queryResolver{r}
 }
@@ -51,6 +56,40 @@ func (r *Resolver) AgentSendMessage(ctx context.Context, messageInput *model.Mes
 	return subscriptionChan, nil
 }
 
+func (r *Resolver) CancelTask(ctx context.Context, taskID string) (*model.TaskStatusUpdate, error) {
+	task, err := r.a2aClient.CancelTasks(ctx, protocol.TaskIDParams{ID: taskID})
+	if err != nil {
+		fmt.Printf("Cancel Task Request Failed %s", err)
+		return nil, err
+	}
+
+	status := &model.TaskStatus{
+		State:     string(task.Status.State),
+		Timestamp: &task.Status.Timestamp,
+	}
+	if task.Status.Message != nil {
+		var parts []model.Part
+		for _, part := range task.Status.Message.Parts {
+			if p, ok := part.(*protocol.TextPart); ok {
+				parts = append(parts, model.TextPart{Text: p.Text})
+			}
+		}
+		status.Message = &model.Message{
+			MessageID: task.Status.Message.MessageID,
+			TaskID:    task.ID,
+			ContextID: task.ContextID,
+			Role:      "agent",
+			Parts:     parts,
+		}
+	}
+
+	return &model.TaskStatusUpdate{
+		TaskID:    &task.ID,
+		ContextID: &task.ContextID,
+		Status:    status,
+	}, nil
+}
+
 func processAgentResponses(ctx context.Context, agentChan <-chan protocol.StreamingMessageEvent, subscriptionChan chan<- *model.AgentResponse) {
 
 	defer close(subscriptionChan)
@@ -84,7 +123,7 @@ func processAgentResponses(ctx context.Context, agentChan <-chan protocol.Stream
 						case *protocol.FilePart:
 						case *protocol.DataPart:
 						default:
-							fmt.Printf("%sUnsupported part type: %T\n", p)
+							fmt.Printf("Unsupported part type: %T\n", p)
 						}
 					}
 
@@ -117,7 +156,9 @@ func processAgentResponses(ctx context.Context, agentChan <-chan protocol.Stream
 				}
 
 			case *protocol.TaskArtifactUpdateEvent:
-				fmt.Printf("  [Artifact Update: %s (Last Chunk %b)]\n\n", *e.Artifact.Name, *e.LastChunk)
+				if e.Append == nil || !*e.Append {
+					fmt.Printf("  [Artifact Update: %s]\n\n", e.Artifact.ArtifactID)
+				}
 
 				var parts []model.Part
 				for _, part := range e.Artifact.Parts {
@@ -127,7 +168,7 @@ func processAgentResponses(ctx context.Context, agentChan <-chan protocol.Stream
 					case *protocol.FilePart:
 					case *protocol.DataPart:
 					default:
-						fmt.Printf("%sUnsupported part type: %T\n", p)
+						fmt.Printf("Unsupported part type: %T\n", p)
 					}
 				}
 
@@ -135,11 +176,14 @@ func processAgentResponses(ctx context.Context, agentChan <-chan protocol.Stream
 					TaskID:    &e.TaskID,
 					ContextID: &e.ContextID,
 					Artifact: &model.Artifact{
-						ArtifactID:  uuid.NewString(),
+						ArtifactID:  e.Artifact.ArtifactID,
 						Name:        e.Artifact.Name,
 						Description: e.Artifact.Description,
 						Parts:       parts,
+						Citations:   citations(e.Artifact.Parts),
 					},
+					Append:    e.Append,
+					LastChunk: e.LastChunk,
 				}
 
 				agentResponse := &model.AgentResponse{
@@ -149,7 +193,7 @@ func processAgentResponses(ctx context.Context, agentChan <-chan protocol.Stream
 				subscriptionChan <- agentResponse
 
 			default:
-				fmt.Println("Warning: received unknown event type: %T\n ", event)
+				fmt.Printf("Warning: received unknown event type: %T\n", event.Result)
 			}
 		}
 
@@ -176,3 +220,15 @@ func createMessageParams(messageInput *model.MessageInput, historyLength int) pr
 
 	return params
 }
+
+func citations(parts []protocol.Part) []*model.Citation {
+	citations := []*model.Citation{}
+	for _, citation := range citation.FromParts(parts) {
+		citations = append(citations, &model.Citation{
+			Number: int32(citation.Number),
+			ID:     citation.ID,
+			Title:  citation.Title,
+		})
+	}
+	return citations
+}
@@ -1,76 +1,64 @@
 package tools
 
 import (
+	"context"
 	_ "embed"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"fusion/internal/llm"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
 )
 
 type AssetDetailsTool struct {
 	Name        string
 	Description string
-	Token       string
-}
-
-var assetDetailsTool = &AssetDetailsTool{
-	Name:        "asset_details",
-	Description: "Provides the details of an asset e.g. name, owner, operating system, hardware details, etc",
+	Client      *GraphQLClient
+	Schemas     *SchemaProvider
 }
 
 //go:embed asset_details.gql
 var assetDetailsQuery string
 
-func NewAssetDetailsTool(token string) *AssetDetailsTool {
-	assetDetailsTool.Token = token
-	return assetDetailsTool
-}
+//go:embed asset_in_organizations.gql
+var assetInOrganizationsQuery string
 
-func (t *AssetDetailsTool) GenerateToolSchema() *types.ToolMemberToolSpec {
-
-	return &types.ToolMemberToolSpec{
-		Value: types.ToolSpecification{
-			Name:        aws.String(t.Name),
-			Description: aws.String(t.Description),
-			InputSchema: &types.ToolInputSchemaMemberJson{
-				Value: document.NewLazyDocument(map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"assetId": map[string]interface{}{
-							"type":        "string",
-							"description": "User provided identifier for the asset.",
-						},
-					},
-					"required": []interface{}{"assetId"},
-				}),
-			},
-		},
+func NewAssetDetailsTool(client *GraphQLClient, schemas *SchemaProvider) *AssetDetailsTool {
+	return &AssetDetailsTool{
+		Name:        "asset_details",
+		Description: "Provides the details of an asset e.g. name, owner, operating system, hardware details, etc",
+		Client:      client,
+		Schemas:     schemas,
 	}
 }
 
-func (t *AssetDetailsTool) Call(toolCall *types.ContentBlockMemberToolUse) (*types.Message, error) {
-	var parameters map[string]interface{}
+func (t *AssetDetailsTool) GenerateToolSchema() llm.ToolSpec {
 
-	if toolCall.Value.Input != nil {
-		err := toolCall.Value.Input.UnmarshalSmithyDocument(&parameters)
-		if err != nil {
-			fmt.Errorf("tool call failed. unable to marshal parameters: %w", err)
-			return nil, err
-		}
+	return llm.ToolSpec{
+		Name:        t.Name,
+		Description: t.Description,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"assetId": map[string]interface{}{
+					"type":        "string",
+					"description": "User provided identifier for the asset.",
+				},
+			},
+			"required": []interface{}{"assetId"},
+		},
 	}
+}
 
-	if parameters == nil || parameters["assetId"] == nil {
-		err := errors.New("tool call failed. missing input parameter")
-		fmt.Errorf("tool call failed. no asset ID provided by the model: %w", err)
+func (t *AssetDetailsTool) Call(ctx context.Context, toolCall *llm.ToolUseBlock) (*llm.ToolResultBlock, error) {
+	invocation, err := InvocationFromContext(ctx)
+	if err != nil {
 		return nil, err
 	}
 
-	assetId, ok := parameters["assetId"].(string)
-	if !ok || assetId == "" {
-		err := errors.New("tool call failed. invalid parameter")
-		fmt.Errorf("tool call failed. invalid parameter: %w", err)
+	assetId, err := stringParameter(toolCall, "assetId")
+	if err != nil {
 		return nil, err
 	}
 
@@ -78,27 +66,99 @@ func (t *AssetDetailsTool) Call(toolCall *types.ContentBlockMemberToolUse) (*typ
 		"id": assetId,
 	}
 
-	result, err := executeQuery(graphQLBody{Query: assetDetailsQuery, Variables: variables}, t.Token)
+	query := assetDetailsQuery
+	verify := false
+	if invocation.Organizations != nil {
+		query, verify = t.scopedQuery(ctx, invocation)
+	}
+
+	response, err := t.Client.Execute(ctx, invocation.Token, GraphQLRequest{Query: query, Variables: variables})
 	if err != nil {
-		fmt.Println("Failed to execute query")
-		return nil, fmt.Errorf("query execution")
-	}
-
-	content := document.NewLazyDocument(map[string]interface{}{"asset": result})
-
-	return &types.Message{
-		Role: "user",
-		Content: []types.ContentBlock{
-			&types.ContentBlockMemberToolResult{
-				Value: types.ToolResultBlock{
-					ToolUseId: toolCall.Value.ToolUseId,
-					Content: []types.ToolResultContentBlock{
-						&types.ToolResultContentBlockMemberJson{
-							Value: content,
-						},
-					},
-				},
-			},
-		},
+		fmt.Printf("Failed to execute asset details query: %v\n", err)
+		return nil, err
+	}
+
+	if verify {
+		if err := t.verifyOrganization(ctx, invocation, response); err != nil {
+			return nil, err
+		}
+	}
+
+	return &llm.ToolResultBlock{
+		ToolUseID: toolCall.ID,
+		JSON:      map[string]interface{}{"asset": string(response.Raw)},
 	}, nil
 }
+
+// scopedQuery limits the asset details query to the caller's organizations when the API takes them
+// for the asset field. Otherwise the query is run as it is and reports that the asset it returns
+// must be verified to be in the caller's organizations.
+func (t *AssetDetailsTool) scopedQuery(ctx context.Context, invocation *Invocation) (string, bool) {
+	document, err := parser.ParseQuery(&ast.Source{Input: assetDetailsQuery})
+	if err != nil {
+		return assetDetailsQuery, true
+	}
+
+	var schemaAST *ast.Schema
+	if schema, err := t.Schemas.Schema(ctx, invocation); err != nil {
+		fmt.Printf("Asset schema unavailable, asset details will be checked against the organizations: %v\n", err)
+	} else {
+		schemaAST = schema.AST
+	}
+
+	if err := scopeQuery(schemaAST, document, invocation.Organizations); err != nil {
+		return assetDetailsQuery, true
+	}
+	return formatQuery(document), false
+}
+
+// verifyOrganization checks that the asset of a details response is in the caller's organizations by
+// searching for it by name in those organizations only. The details are not returned otherwise.
+func (t *AssetDetailsTool) verifyOrganization(ctx context.Context, invocation *Invocation, response *GraphQLResponse) error {
+	var details struct {
+		Asset *struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"asset"`
+	}
+	if err := json.Unmarshal(response.Data, &details); err != nil {
+		return fmt.Errorf("tool call failed. failed to read the asset details: %w", err)
+	}
+	if details.Asset == nil {
+		return nil
+	}
+	if len(invocation.Organizations) == 0 {
+		return errors.New("tool call failed. the caller has no organizations whose assets can be searched")
+	}
+
+	search, err := t.Client.Execute(ctx, invocation.Token, GraphQLRequest{
+		Query: assetInOrganizationsQuery,
+		Variables: map[string]interface{}{
+			"name":          details.Asset.Name,
+			"organizations": invocation.Organizations,
+		},
+	})
+	if err != nil {
+		fmt.Printf("Failed to check the organization of asset %s: %v\n", details.Asset.ID, err)
+		return fmt.Errorf("tool call failed. the organization of the asset could not be checked: %w", err)
+	}
+
+	var found struct {
+		AssetSearch struct {
+			Nodes []struct {
+				ID string `json:"id"`
+			} `json:"nodes"`
+		} `json:"assetSearch"`
+	}
+	if err := json.Unmarshal(search.Data, &found); err != nil {
+		return fmt.Errorf("tool call failed. the organization of the asset could not be checked: %w", err)
+	}
+	for _, node := range found.AssetSearch.Nodes {
+		if node.ID == details.Asset.ID {
+			return nil
+		}
+	}
+
+	fmt.Printf("Organization Scope: details of asset %s rejected\n", details.Asset.ID)
+	return fmt.Errorf("tool call failed. asset %s is not in the caller's organizations", details.Asset.ID)
+}
@@ -1,96 +1,184 @@
 package tools
 
 import (
-	"errors"
+	"context"
 	"fmt"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"fusion/internal/llm"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
 )
 
 type ExecuteQueryTool struct {
 	Name        string
 	Description string
-	Token       string
+	Client      *GraphQLClient
+	Schemas     *SchemaProvider
+	// Pagination caps automatic pagination. Limits requested by the model are clamped to it.
+	Pagination PaginationLimits
+	// Policy decides which operations are sent to the API. Every operation is sent when it is nil.
+	Policy *QueryPolicy
 }
 
-var executeQueryTool = &ExecuteQueryTool{
-	Name:        "execute_query",
-	Description: "Executes a GraphQL Query using the n-able public API. Provides support for sophisticated searching of assets.",
+func NewExecuteQueryTool(client *GraphQLClient, schemas *SchemaProvider, pagination PaginationLimits, policy *QueryPolicy) *ExecuteQueryTool {
+	return &ExecuteQueryTool{
+		Name:        "execute_query",
+		Description: "Executes a GraphQL Query using the n-able public API. Provides support for sophisticated searching of assets.",
+		Client:      client,
+		Schemas:     schemas,
+		Pagination:  pagination,
+		Policy:      policy,
+	}
 }
 
-func NewExecuteQueryTool(token string) *ExecuteQueryTool {
-	executeQueryTool.Token = token
-	return executeQueryTool
-}
+func (t *ExecuteQueryTool) GenerateToolSchema() llm.ToolSpec {
 
-func (t *ExecuteQueryTool) GenerateToolSchema() *types.ToolMemberToolSpec {
-
-	return &types.ToolMemberToolSpec{
-		Value: types.ToolSpecification{
-			Name:        aws.String(t.Name),
-			Description: aws.String(t.Description),
-			InputSchema: &types.ToolInputSchemaMemberJson{
-				Value: document.NewLazyDocument(map[string]interface{}{
-					"type": "object",
-					"properties": map[string]interface{}{
-						"query": map[string]interface{}{
-							"type":        "string",
-							"description": "A GraphQL Query that will be executed to search for managed assets and return their details.",
-						},
-					},
-					"required": []interface{}{"query"},
-				}),
+	return llm.ToolSpec{
+		Name:        t.Name,
+		Description: t.Description,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "A GraphQL Query that will be executed to search for managed assets and return their details.",
+				},
+				"paginate": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Follow the assetSearch cursors and merge every page into one result. The query must select assetSearch and no other field. Use it when counting or aggregating assets rather than reading the first page only.",
+				},
+				"maxItems": map[string]interface{}{
+					"type":        "integer",
+					"description": limitDescription("The maximum number of assets to fetch when paginating.", t.Pagination.MaxItems),
+				},
+				"maxPages": map[string]interface{}{
+					"type":        "integer",
+					"description": limitDescription("The maximum number of pages to fetch when paginating.", t.Pagination.MaxPages),
+				},
 			},
+			"required": []interface{}{"query"},
 		},
 	}
 }
 
-func (t *ExecuteQueryTool) Call(toolCall *types.ContentBlockMemberToolUse) (*types.Message, error) {
-	var parameters map[string]interface{}
+func (t *ExecuteQueryTool) Call(ctx context.Context, toolCall *llm.ToolUseBlock) (*llm.ToolResultBlock, error) {
+	invocation, err := InvocationFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-	if toolCall.Value.Input != nil {
-		err := toolCall.Value.Input.UnmarshalSmithyDocument(&parameters)
-		if err != nil {
-			fmt.Errorf("tool call failed. unable to marshal parameters: %w", err)
-			return nil, err
-		}
+	query, err := stringParameter(toolCall, "query")
+	if err != nil {
+		return nil, err
 	}
 
-	if parameters == nil || parameters["query"] == nil {
-		err := errors.New("tool call failed. missing input parameter")
-		fmt.Errorf("tool call failed. no query provided by the model: %w", err)
+	paginate, err := boolParameter(toolCall, "paginate")
+	if err != nil {
 		return nil, err
 	}
 
-	query, ok := parameters["query"].(string)
-	if !ok || query == "" {
-		err := errors.New("tool call failed. invalid parameter")
-		fmt.Errorf("tool call failed. invalid parameter: %w", err)
+	limits, err := t.paginationLimits(toolCall)
+	if err != nil {
 		return nil, err
 	}
 
-	result, err := executeQuery(graphQLBody{Query: query}, t.Token)
+	// The operation is checked against the policy before anything is sent to the API, even when the
+	// schema cannot be loaded to validate it.
+	var queryDocument *ast.QueryDocument
+	if t.Policy != nil {
+		if queryDocument, err = t.Policy.Check(ctx, t.Name, query); err != nil {
+			return nil, err
+		}
+	}
+
+	var schemaAST *ast.Schema
+	schema, err := t.Schemas.Schema(ctx, invocation)
 	if err != nil {
-		fmt.Println("Failed to execute query")
-		return nil, fmt.Errorf("query execution")
-	}
-
-	content := document.NewLazyDocument(map[string]interface{}{"assets": result})
-
-	return &types.Message{
-		Role: "user",
-		Content: []types.ContentBlock{
-			&types.ContentBlockMemberToolResult{
-				Value: types.ToolResultBlock{
-					ToolUseId: toolCall.Value.ToolUseId,
-					Content: []types.ToolResultContentBlock{
-						&types.ToolResultContentBlockMemberJson{
-							Value: content,
-						},
-					},
-				},
-			},
-		},
+		fmt.Printf("Asset schema unavailable, executing query without validation: %v\n", err)
+	} else if validated, err := validateQuery(schema.AST, query); err != nil {
+		// The embedded snapshot can be older than the live schema, so a query it rejects may still be
+		// valid and is left for the API to check.
+		if schema.Source != SchemaSourceEmbedded {
+			fmt.Printf("Query rejected by validation: %v\n", err)
+			return nil, err
+		}
+		fmt.Printf("Query does not validate against the embedded schema, executing it anyway: %v\n", err)
+	} else {
+		queryDocument = validated
+		schemaAST = schema.AST
+	}
+
+	if queryDocument == nil && (paginate || invocation.Organizations != nil) {
+		var parseErr error
+		if queryDocument, parseErr = parser.ParseQuery(&ast.Source{Input: query}); parseErr != nil {
+			return nil, fmt.Errorf("query could not be parsed: %w", parseErr)
+		}
+	}
+
+	// The query is rewritten to search only the caller's organizations before it reaches the API.
+	if invocation.Organizations != nil {
+		if err := scopeQuery(schemaAST, queryDocument, invocation.Organizations); err != nil {
+			return nil, err
+		}
+		query = formatQuery(queryDocument)
+	}
+
+	result := map[string]interface{}{}
+
+	if paginate {
+		paginated, err := preparePagination(queryDocument)
+		if err != nil {
+			return nil, err
+		}
+
+		data, summary, err := executePaginated(ctx, t.Client, invocation.Token, paginated, limits)
+		if err != nil {
+			fmt.Printf("Failed to execute paginated query: %v\n", err)
+			return nil, err
+		}
+		fmt.Printf("Execute Query: fetched %d items in %d pages, truncated: %t\n", summary.Items, summary.Pages, summary.Truncated)
+
+		result["assets"] = string(data)
+		result["pagination"] = summary
+	} else {
+		response, err := t.Client.Execute(ctx, invocation.Token, GraphQLRequest{Query: query})
+		if err != nil {
+			fmt.Printf("Failed to execute query: %v\n", err)
+			return nil, err
+		}
+		result["assets"] = string(response.Raw)
+	}
+
+	return &llm.ToolResultBlock{
+		ToolUseID: toolCall.ID,
+		JSON:      result,
 	}, nil
 }
+
+func limitDescription(description string, limit int) string {
+	if limit == 0 {
+		return description
+	}
+	return fmt.Sprintf("%s At most %d.", description, limit)
+}
+
+func (t *ExecuteQueryTool) paginationLimits(toolCall *llm.ToolUseBlock) (PaginationLimits, error) {
+	limits := t.Pagination
+
+	maxItems, err := intParameter(toolCall, "maxItems")
+	if err != nil {
+		return limits, err
+	}
+	if maxItems > 0 && (limits.MaxItems == 0 || maxItems < limits.MaxItems) {
+		limits.MaxItems = maxItems
+	}
+
+	maxPages, err := intParameter(toolCall, "maxPages")
+	if err != nil {
+		return limits, err
+	}
+	if maxPages > 0 && (limits.MaxPages == 0 || maxPages < limits.MaxPages) {
+		limits.MaxPages = maxPages
+	}
+
+	return limits, nil
+}
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"fusion/internal/llm"
+	"unicode/utf8"
+)
+
+type FetchResultTool struct {
+	Name        string
+	Description string
+	Results     ResultStore
+	// ChunkSize is the number of characters returned per call.
+	ChunkSize int
+}
+
+func NewFetchResultTool(results ResultStore, chunkSize int) *FetchResultTool {
+	return &FetchResultTool{
+		Name:        "fetch_result",
+		Description: "Reads more of a tool result that was truncated. Use the handle and offset given in the truncated result.",
+		Results:     results,
+		ChunkSize:   chunkSize,
+	}
+}
+
+func (t *FetchResultTool) GenerateToolSchema() llm.ToolSpec {
+
+	return llm.ToolSpec{
+		Name:        t.Name,
+		Description: t.Description,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"handle": map[string]interface{}{
+					"type":        "string",
+					"description": "The handle of the truncated result.",
+				},
+				"offset": map[string]interface{}{
+					"type":        "integer",
+					"description": "The character offset to read from.",
+				},
+			},
+			"required": []interface{}{"handle"},
+		},
+	}
+}
+
+func (t *FetchResultTool) Call(ctx context.Context, toolCall *llm.ToolUseBlock) (*llm.ToolResultBlock, error) {
+	invocation, err := InvocationFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := stringParameter(toolCall, "handle")
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := intParameterAtLeast(toolCall, "offset", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	content, ok, err := t.Results.Get(ctx, invocation.Scope(), handle)
+	if err != nil {
+		return nil, fmt.Errorf("tool call failed. %w", err)
+	}
+	if !ok {
+		return nil, errors.New("tool call failed. the result has expired or the handle is unknown")
+	}
+	if offset >= len(content) {
+		return nil, fmt.Errorf("tool call failed. offset %d is past the end of the result, which has %d characters", offset, len(content))
+	}
+
+	chunk, offset := ResultChunk(content, offset, t.ChunkSize)
+	next := offset + len(chunk)
+
+	text := chunk
+	if next < len(content) {
+		text += fmt.Sprintf("\n\n[Characters %d to %d of %d. Call fetch_result with handle %q and offset %d to read more.]", offset, next, len(content), handle, next)
+	} else {
+		text += fmt.Sprintf("\n\n[Characters %d to %d of %d. This is the end of the result.]", offset, next, len(content))
+	}
+
+	return &llm.ToolResultBlock{
+		ToolUseID: toolCall.ID,
+		Text:      text,
+	}, nil
+}
+
+// ResultChunk returns at most size bytes of content from offset without splitting a character, and
+// the offset the chunk starts at.
+func ResultChunk(content string, offset int, size int) (string, int) {
+	for offset > 0 && offset < len(content) && !utf8.RuneStart(content[offset]) {
+		offset--
+	}
+	end := offset + size
+	if end >= len(content) {
+		return content[offset:], offset
+	}
+	for end > offset && !utf8.RuneStart(content[end]) {
+		end--
+	}
+	return content[offset:end], offset
+}
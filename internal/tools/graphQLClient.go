@@ -0,0 +1,180 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ProductionEndpoint = "https://api.n-able.com/graphql"
+	StagingEndpoint    = "https://stg.api.n-able.com/graphql"
+)
+
+const defaultQueryTimeout = 10 * time.Second
+
+// sharedTransport is used by every client so that connections to the API are pooled across tasks.
+var sharedTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}).DialContext,
+	ForceAttemptHTTP2:     true,
+	MaxIdleConns:          100,
+	MaxIdleConnsPerHost:   20,
+	IdleConnTimeout:       90 * time.Second,
+	TLSHandshakeTimeout:   5 * time.Second,
+	ExpectContinueTimeout: time.Second,
+}
+
+type GraphQLClient struct {
+	Endpoint   string
+	HTTPClient *http.Client
+	// Timeout applies to calls whose context has no deadline of its own.
+	Timeout time.Duration
+}
+
+type GraphQLClientOption func(*GraphQLClient)
+
+func WithHTTPClient(httpClient *http.Client) GraphQLClientOption {
+	return func(c *GraphQLClient) {
+		c.HTTPClient = httpClient
+	}
+}
+
+func WithQueryTimeout(timeout time.Duration) GraphQLClientOption {
+	return func(c *GraphQLClient) {
+		c.Timeout = timeout
+	}
+}
+
+// NewGraphQLClient creates a client for the given endpoint. The names "prod" and "staging" select
+// the public N-able endpoints, anything else is used as the URL, e.g. a local stub.
+func NewGraphQLClient(endpoint string, options ...GraphQLClientOption) *GraphQLClient {
+	client := &GraphQLClient{
+		Endpoint:   ResolveEndpoint(endpoint),
+		HTTPClient: &http.Client{Transport: sharedTransport},
+		Timeout:    defaultQueryTimeout,
+	}
+
+	for _, option := range options {
+		option(client)
+	}
+
+	return client
+}
+
+func ResolveEndpoint(endpoint string) string {
+	switch strings.ToLower(endpoint) {
+	case "", "staging", "stg":
+		return StagingEndpoint
+	case "prod", "production":
+		return ProductionEndpoint
+	default:
+		return endpoint
+	}
+}
+
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+type GraphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphQLErrors   `json:"errors"`
+	// Raw holds the response body as it was received.
+	Raw []byte `json:"-"`
+}
+
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, graphQLErr := range e {
+		if len(graphQLErr.Path) > 0 {
+			messages = append(messages, fmt.Sprintf("%s (path: %v)", graphQLErr.Message, graphQLErr.Path))
+			continue
+		}
+		messages = append(messages, graphQLErr.Message)
+	}
+	return "query returned errors: " + strings.Join(messages, "; ")
+}
+
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("query failed with HTTP status %s: %s", e.Status, truncate(e.Body, 2000))
+}
+
+// Execute sends the request with the caller's bearer token. Non-2xx responses are returned as an
+// *HTTPError and responses with an errors array as GraphQLErrors.
+func (c *GraphQLClient) Execute(ctx context.Context, token string, request GraphQLRequest) (*GraphQLResponse, error) {
+	if _, ok := ctx.Deadline(); !ok && c.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the query: %w", err)
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the query request: %w", err)
+	}
+	httpRequest.Header.Add("Authorization", "Bearer "+token)
+	httpRequest.Header.Add("Accept", "application/json")
+	httpRequest.Header.Add("Content-Type", "application/json")
+
+	httpResponse, err := c.HTTPClient.Do(httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("query request failed: %w", err)
+	}
+	defer httpResponse.Body.Close()
+
+	body, err := io.ReadAll(httpResponse.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the query response: %w", err)
+	}
+
+	if httpResponse.StatusCode < 200 || httpResponse.StatusCode > 299 {
+		return nil, &HTTPError{
+			StatusCode: httpResponse.StatusCode,
+			Status:     httpResponse.Status,
+			Body:       string(body),
+		}
+	}
+
+	var response GraphQLResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("query returned a response that is not valid JSON: %w", err)
+	}
+	response.Raw = body
+
+	if len(response.Errors) > 0 {
+		return &response, response.Errors
+	}
+
+	return &response, nil
+}
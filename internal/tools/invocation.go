@@ -0,0 +1,60 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fusion/internal/citation"
+	"sort"
+	"strings"
+	"trpc.group/trpc-go/trpc-a2a-go/taskmanager"
+)
+
+// Invocation holds the state of the task a tool call is made on behalf of. Tools are shared
+// between tasks, so anything task specific must be read from here rather than stored on the tool.
+type Invocation struct {
+	Handle    taskmanager.TaskHandler
+	TaskID    string
+	ContextID *string
+	Token     string
+	// CallerID identifies the authenticated caller in audit records.
+	CallerID string
+	// Organizations are the organizations whose assets the caller may see. The caller is not
+	// limited to any when it is nil.
+	Organizations []string
+	// Citations collects the knowledge articles used while answering.
+	Citations *citation.Citations
+}
+
+// Scope identifies the caller together with the organizations it may see. What is kept for a caller
+// is only given back to the same scope.
+func (i *Invocation) Scope() string {
+	return CallerScope(i.CallerID, i.Organizations)
+}
+
+// CallerScope is the scope of a caller limited to the given organizations, or to none when they are nil.
+func CallerScope(callerID string, organizations []string) string {
+	names := "*"
+	if organizations != nil {
+		sorted := append([]string{}, organizations...)
+		sort.Strings(sorted)
+		names = strings.Join(sorted, ",")
+	}
+	sum := sha256.Sum256([]byte(callerID + "\n" + names))
+	return hex.EncodeToString(sum[:])
+}
+
+type invocationKey struct{}
+
+func WithInvocation(ctx context.Context, invocation *Invocation) context.Context {
+	return context.WithValue(ctx, invocationKey{}, invocation)
+}
+
+func InvocationFromContext(ctx context.Context) (*Invocation, error) {
+	invocation, ok := ctx.Value(invocationKey{}).(*Invocation)
+	if !ok || invocation == nil {
+		return nil, errors.New("tool call failed. no task invocation in context")
+	}
+	return invocation, nil
+}
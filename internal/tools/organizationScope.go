@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/formatter"
+	"strings"
+)
+
+// organizationsArgument is the argument that limits a query field to a set of organizations.
+const organizationsArgument = "inOrganizations"
+
+// scopeQuery limits a query to the caller's organizations. Every root field must take the
+// inOrganizations argument: a field without it is given the caller's organizations, and a field
+// naming any other organization is rejected. When the schema is nil only assetSearch is known to
+// take the argument.
+func scopeQuery(schema *ast.Schema, document *ast.QueryDocument, organizations []string) error {
+	for _, operation := range document.Operations {
+		if operation.Operation != ast.Query {
+			return fmt.Errorf("tool call failed. only queries can be run, not %s operations", operation.Operation)
+		}
+		if err := scopeSelections(schema, document, operation.SelectionSet, organizations, map[string]bool{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scopeSelections(schema *ast.Schema, document *ast.QueryDocument, selections ast.SelectionSet, organizations []string, visited map[string]bool) error {
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			if err := scopeField(schema, s, organizations); err != nil {
+				return err
+			}
+		case *ast.InlineFragment:
+			if err := scopeSelections(schema, document, s.SelectionSet, organizations, visited); err != nil {
+				return err
+			}
+		case *ast.FragmentSpread:
+			if visited[s.Name] {
+				continue
+			}
+			visited[s.Name] = true
+			fragment := document.Fragments.ForName(s.Name)
+			if fragment == nil {
+				return fmt.Errorf("tool call failed. fragment %s is not defined", s.Name)
+			}
+			if err := scopeSelections(schema, document, fragment.SelectionSet, organizations, visited); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func scopeField(schema *ast.Schema, field *ast.Field, organizations []string) error {
+	if strings.HasPrefix(field.Name, "__") {
+		return nil
+	}
+	if !takesOrganizations(schema, field.Name) {
+		return fmt.Errorf("tool call failed. %s cannot be limited to the caller's organizations, so it cannot be queried. search with %s instead", field.Name, paginatedField)
+	}
+	if len(organizations) == 0 {
+		return errors.New("tool call failed. the caller has no organizations whose assets can be searched")
+	}
+
+	argument := field.Arguments.ForName(organizationsArgument)
+	if argument == nil {
+		field.Arguments = append(field.Arguments, &ast.Argument{Name: organizationsArgument, Value: organizationsValue(organizations)})
+		return nil
+	}
+
+	var requested []*ast.Value
+	switch argument.Value.Kind {
+	case ast.NullValue:
+	case ast.ListValue:
+		for _, child := range argument.Value.Children {
+			requested = append(requested, child.Value)
+		}
+	default:
+		requested = []*ast.Value{argument.Value}
+	}
+	if len(requested) == 0 {
+		argument.Value = organizationsValue(organizations)
+		return nil
+	}
+
+	for _, value := range requested {
+		if value.Kind != ast.StringValue && value.Kind != ast.IntValue {
+			return fmt.Errorf("tool call failed. %s of %s must list organization IDs", organizationsArgument, field.Name)
+		}
+		if !Contains(organizations, value.Raw) {
+			fmt.Printf("Organization Scope: query for organization %s rejected\n", value.Raw)
+			return fmt.Errorf("tool call failed. organization %s is not one of the caller's organizations. use %s or leave %s out to search all of them", value.Raw, strings.Join(organizations, ", "), organizationsArgument)
+		}
+	}
+	return nil
+}
+
+func takesOrganizations(schema *ast.Schema, name string) bool {
+	if schema == nil || schema.Query == nil {
+		return name == paginatedField
+	}
+	definition := schema.Query.Fields.ForName(name)
+	return definition != nil && definition.Arguments.ForName(organizationsArgument) != nil
+}
+
+func organizationsValue(organizations []string) *ast.Value {
+	value := &ast.Value{Kind: ast.ListValue}
+	for _, organization := range organizations {
+		value.Children = append(value.Children, &ast.ChildValue{Value: &ast.Value{Kind: ast.StringValue, Raw: organization}})
+	}
+	return value
+}
+
+func formatQuery(document *ast.QueryDocument) string {
+	var query bytes.Buffer
+	formatter.NewFormatter(&query).FormatQueryDocument(document)
+	return query.String()
+}
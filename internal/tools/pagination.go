@@ -0,0 +1,277 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/formatter"
+)
+
+const (
+	paginatedField     = "assetSearch"
+	paginationVariable = "fusionAfter"
+)
+
+// PaginationLimits bound how much a paginated query fetches. A zero limit is not enforced.
+type PaginationLimits struct {
+	MaxPages int
+	MaxItems int
+}
+
+var DefaultPaginationLimits = PaginationLimits{
+	MaxPages: 10,
+	MaxItems: 500,
+}
+
+type paginationSummary struct {
+	Pages      int  `json:"pages"`
+	Items      int  `json:"items"`
+	TotalCount *int `json:"totalCount,omitempty"`
+	Truncated  bool `json:"truncated"`
+}
+
+type paginatedQuery struct {
+	query       string
+	responseKey string
+	cursor      *string
+}
+
+// preparePagination rewrites the assetSearch field of a validated query so that its cursor is taken
+// from a variable and the page info and edge cursors needed to fetch the next page are returned.
+// The field must be the only field of the query, as it is fetched once per page.
+func preparePagination(document *ast.QueryDocument) (*paginatedQuery, error) {
+	var operation *ast.OperationDefinition
+	for _, candidate := range document.Operations {
+		if candidate.Operation == ast.Query {
+			operation = candidate
+			break
+		}
+	}
+	if operation == nil {
+		return nil, errors.New("pagination requires a query operation")
+	}
+
+	var field *ast.Field
+	if len(operation.SelectionSet) == 1 {
+		field, _ = operation.SelectionSet[0].(*ast.Field)
+	}
+	if field == nil || field.Name != paginatedField {
+		return nil, fmt.Errorf("pagination is only supported for queries with a single %s field. Run the other fields in a separate query without paginate", paginatedField)
+	}
+
+	paginated := &paginatedQuery{
+		responseKey: field.Alias,
+	}
+	if paginated.responseKey == "" {
+		paginated.responseKey = field.Name
+	}
+
+	var arguments ast.ArgumentList
+	for _, argument := range field.Arguments {
+		switch argument.Name {
+		case "after":
+			if argument.Value.Kind == ast.StringValue {
+				cursor := argument.Value.Raw
+				paginated.cursor = &cursor
+			}
+			continue
+		}
+		arguments = append(arguments, argument)
+	}
+	field.Arguments = append(arguments, &ast.Argument{
+		Name:  "after",
+		Value: &ast.Value{Kind: ast.Variable, Raw: paginationVariable},
+	})
+
+	var variables ast.VariableDefinitionList
+	for _, variable := range operation.VariableDefinitions {
+		if variable.Variable != paginationVariable {
+			variables = append(variables, variable)
+		}
+	}
+	operation.VariableDefinitions = append(variables, &ast.VariableDefinition{
+		Variable: paginationVariable,
+		Type:     ast.NamedType("String", nil),
+	})
+
+	ensurePageInfo(field)
+	ensureEdgeCursor(field)
+
+	var query bytes.Buffer
+	formatter.NewFormatter(&query).FormatQueryDocument(document)
+	paginated.query = query.String()
+
+	return paginated, nil
+}
+
+func ensurePageInfo(field *ast.Field) {
+	for _, selection := range field.SelectionSet {
+		pageInfo, ok := selection.(*ast.Field)
+		if !ok || pageInfo.Name != "pageInfo" || (pageInfo.Alias != "" && pageInfo.Alias != "pageInfo") {
+			continue
+		}
+		ensureSelected(pageInfo, "hasNextPage")
+		ensureSelected(pageInfo, "endCursor")
+		return
+	}
+
+	field.SelectionSet = append(field.SelectionSet, &ast.Field{
+		Name:         "pageInfo",
+		SelectionSet: ast.SelectionSet{&ast.Field{Name: "hasNextPage"}, &ast.Field{Name: "endCursor"}},
+	})
+}
+
+func ensureSelected(field *ast.Field, name string) {
+	for _, selection := range field.SelectionSet {
+		if selected, ok := selection.(*ast.Field); ok && selected.Name == name && (selected.Alias == "" || selected.Alias == name) {
+			return
+		}
+	}
+	field.SelectionSet = append(field.SelectionSet, &ast.Field{Name: name})
+}
+
+func ensureEdgeCursor(field *ast.Field) {
+	for _, selection := range field.SelectionSet {
+		edges, ok := selection.(*ast.Field)
+		if !ok || edges.Name != "edges" || (edges.Alias != "" && edges.Alias != "edges") {
+			continue
+		}
+		ensureSelected(edges, "cursor")
+		return
+	}
+
+	field.SelectionSet = append(field.SelectionSet, &ast.Field{
+		Name:         "edges",
+		SelectionSet: ast.SelectionSet{&ast.Field{Name: "cursor"}},
+	})
+}
+
+// executePaginated follows the end cursor of an assetSearch query until the page info reports no
+// next page or a limit is reached, and returns the merged response. When the API leaves out the page
+// info, the cursor of the last edge is followed until a page is empty.
+func executePaginated(ctx context.Context, client *GraphQLClient, token string, paginated *paginatedQuery, limits PaginationLimits) ([]byte, *paginationSummary, error) {
+	summary := &paginationSummary{}
+
+	var merged map[string]interface{}
+	cursor := paginated.cursor
+
+	for {
+		variables := map[string]interface{}{paginationVariable: nil}
+		if cursor != nil {
+			variables[paginationVariable] = *cursor
+		}
+
+		response, err := client.Execute(ctx, token, GraphQLRequest{Query: paginated.query, Variables: variables})
+		if err != nil {
+			return nil, nil, err
+		}
+		summary.Pages++
+
+		page, err := connectionFromResponse(response, paginated.responseKey)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		edges, _ := page["edges"].([]interface{})
+		if merged == nil {
+			merged = page
+			if totalCount, ok := page["totalCount"].(float64); ok {
+				count := int(totalCount)
+				summary.TotalCount = &count
+			}
+		} else {
+			appendItems(merged, page, "edges")
+			appendItems(merged, page, "nodes")
+			if pageInfo, ok := page["pageInfo"]; ok {
+				merged["pageInfo"] = pageInfo
+			}
+		}
+
+		mergedEdges, _ := merged["edges"].([]interface{})
+		summary.Items = len(mergedEdges)
+
+		pageInfo, _ := page["pageInfo"].(map[string]interface{})
+		complete := len(edges) == 0 ||
+			(summary.TotalCount != nil && summary.Items >= *summary.TotalCount) ||
+			(pageInfo != nil && pageInfo["hasNextPage"] != true)
+		if complete {
+			break
+		}
+
+		if (limits.MaxPages > 0 && summary.Pages >= limits.MaxPages) || (limits.MaxItems > 0 && summary.Items >= limits.MaxItems) {
+			summary.Truncated = true
+			break
+		}
+
+		next := lastCursor(edges)
+		if endCursor, ok := pageInfo["endCursor"].(string); ok && endCursor != "" {
+			next = &endCursor
+		}
+		if next == nil || (cursor != nil && *next == *cursor) {
+			break
+		}
+		cursor = next
+	}
+
+	if limits.MaxItems > 0 && summary.Items > limits.MaxItems {
+		trimItems(merged, "edges", limits.MaxItems)
+		trimItems(merged, "nodes", limits.MaxItems)
+		summary.Items = limits.MaxItems
+		summary.Truncated = true
+	}
+
+	data, err := json.Marshal(map[string]interface{}{
+		"data": map[string]interface{}{paginated.responseKey: merged},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to merge paginated results: %w", err)
+	}
+
+	return data, summary, nil
+}
+
+func connectionFromResponse(response *GraphQLResponse, key string) (map[string]interface{}, error) {
+	var data map[string]map[string]interface{}
+	if err := json.Unmarshal(response.Data, &data); err != nil {
+		return nil, fmt.Errorf("failed to read paginated results: %w", err)
+	}
+
+	connection, ok := data[key]
+	if !ok || connection == nil {
+		return nil, fmt.Errorf("failed to read paginated results: response has no %s field", key)
+	}
+	return connection, nil
+}
+
+func appendItems(merged map[string]interface{}, page map[string]interface{}, key string) {
+	items, ok := page[key].([]interface{})
+	if !ok {
+		return
+	}
+	existing, _ := merged[key].([]interface{})
+	merged[key] = append(existing, items...)
+}
+
+func trimItems(merged map[string]interface{}, key string, length int) {
+	if items, ok := merged[key].([]interface{}); ok && len(items) > length {
+		merged[key] = items[:length]
+	}
+}
+
+func lastCursor(edges []interface{}) *string {
+	if len(edges) == 0 {
+		return nil
+	}
+	edge, ok := edges[len(edges)-1].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	cursor, ok := edge["cursor"].(string)
+	if !ok || cursor == "" {
+		return nil
+	}
+	return &cursor
+}
@@ -0,0 +1,213 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPreparePagination(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+		err   string
+	}{
+		{
+			name:  "injects page info and edge cursors",
+			query: `query { assetSearch(first: 2) { nodes { id } } }`,
+			want:  []string{"after: $fusionAfter", "pageInfo {", "hasNextPage", "endCursor", "edges {", "cursor"},
+		},
+		{
+			name:  "completes selected page info",
+			query: `query { assetSearch { pageInfo { hasNextPage } edges { node { id } } } }`,
+			want:  []string{"hasNextPage", "endCursor", "cursor"},
+		},
+		{
+			name:  "aliased field",
+			query: `query { found: assetSearch(after: "start") { totalCount } }`,
+			want:  []string{"found: assetSearch(after: $fusionAfter)"},
+		},
+		{
+			name:  "other root field",
+			query: `query { assetSearch { totalCount } asset(id: "1") { id } }`,
+			err:   "single assetSearch field",
+		},
+		{
+			name:  "two searches",
+			query: `query { windows: assetSearch { totalCount } linux: assetSearch { totalCount } }`,
+			err:   "single assetSearch field",
+		},
+		{
+			name:  "root fragment",
+			query: `query { ...search } fragment search on Query { assetSearch { totalCount } }`,
+			err:   "single assetSearch field",
+		},
+		{
+			name:  "other field",
+			query: `query { asset(id: "1") { id } }`,
+			err:   "single assetSearch field",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			document, err := parser.ParseQuery(&ast.Source{Input: test.query})
+			if err != nil {
+				t.Fatalf("failed to parse query: %v", err)
+			}
+
+			paginated, err := preparePagination(document)
+			if test.err != "" {
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("got error %v, want %q", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to prepare pagination: %v", err)
+			}
+			for _, want := range test.want {
+				if !strings.Contains(paginated.query, want) {
+					t.Errorf("query %s does not contain %q", paginated.query, want)
+				}
+			}
+		})
+	}
+}
+
+// pageServer answers assetSearch with the given pages in turn. The cursor of each page is its
+// index, and pages without page info leave it out of the response.
+func pageServer(t *testing.T, pageInfo bool, pages ...[]string) (*GraphQLClient, *[]interface{}) {
+	var cursors []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request GraphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("invalid GraphQL request: %v", err)
+		}
+		cursor := request.Variables[paginationVariable]
+		cursors = append(cursors, cursor)
+
+		index := 0
+		if cursor != nil {
+			fmt.Sscanf(cursor.(string), "page-%d", &index)
+			index++
+		}
+		var edges []interface{}
+		if index < len(pages) {
+			for _, id := range pages[index] {
+				edges = append(edges, map[string]interface{}{"cursor": "edge-" + id, "node": map[string]interface{}{"id": id}})
+			}
+		}
+
+		connection := map[string]interface{}{"edges": edges}
+		if pageInfo {
+			connection["pageInfo"] = map[string]interface{}{
+				"hasNextPage": index < len(pages)-1,
+				"endCursor":   fmt.Sprintf("page-%d", index),
+			}
+		} else if len(edges) > 0 {
+			// Without page info the client follows the cursor of the last edge.
+			edges[len(edges)-1].(map[string]interface{})["cursor"] = fmt.Sprintf("page-%d", index)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"assetSearch": connection}})
+	}))
+	t.Cleanup(server.Close)
+	return NewGraphQLClient(server.URL), &cursors
+}
+
+func TestExecutePaginated(t *testing.T) {
+	// The pages are shorter than the page size asked for, and not all of the same length.
+	pages := [][]string{{"1", "2"}, {"3"}, {"4", "5"}}
+
+	tests := []struct {
+		name     string
+		pageInfo bool
+		limits   PaginationLimits
+		want     string
+		cursors  string
+		summary  paginationSummary
+	}{
+		{
+			name:     "page info",
+			pageInfo: true,
+			want:     "[1 2 3 4 5]",
+			cursors:  "[<nil> page-0 page-1]",
+			summary:  paginationSummary{Pages: 3, Items: 5},
+		},
+		{
+			name:     "edge cursors",
+			pageInfo: false,
+			want:     "[1 2 3 4 5]",
+			cursors:  "[<nil> page-0 page-1 page-2]",
+			summary:  paginationSummary{Pages: 4, Items: 5},
+		},
+		{
+			name:     "page limit",
+			pageInfo: true,
+			limits:   PaginationLimits{MaxPages: 2},
+			want:     "[1 2 3]",
+			cursors:  "[<nil> page-0]",
+			summary:  paginationSummary{Pages: 2, Items: 3, Truncated: true},
+		},
+		{
+			name:     "item limit",
+			pageInfo: true,
+			limits:   PaginationLimits{MaxItems: 4},
+			want:     "[1 2 3 4]",
+			cursors:  "[<nil> page-0 page-1]",
+			summary:  paginationSummary{Pages: 3, Items: 4, Truncated: true},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client, cursors := pageServer(t, test.pageInfo, pages...)
+			document, err := parser.ParseQuery(&ast.Source{Input: `query { assetSearch(first: 10) { edges { node { id } } } }`})
+			if err != nil {
+				t.Fatalf("failed to parse query: %v", err)
+			}
+			paginated, err := preparePagination(document)
+			if err != nil {
+				t.Fatalf("failed to prepare pagination: %v", err)
+			}
+
+			data, summary, err := executePaginated(context.Background(), client, "token", paginated, test.limits)
+			if err != nil {
+				t.Fatalf("failed to execute paginated query: %v", err)
+			}
+
+			var response struct {
+				Data struct {
+					AssetSearch struct {
+						Edges []struct {
+							Node struct {
+								ID string `json:"id"`
+							} `json:"node"`
+						} `json:"edges"`
+					} `json:"assetSearch"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(data, &response); err != nil {
+				t.Fatalf("failed to read merged response: %v", err)
+			}
+			var ids []string
+			for _, edge := range response.Data.AssetSearch.Edges {
+				ids = append(ids, edge.Node.ID)
+			}
+			if fmt.Sprint(ids) != test.want {
+				t.Errorf("got assets %v, want %s", ids, test.want)
+			}
+			if fmt.Sprint(*cursors) != test.cursors {
+				t.Errorf("requested cursors %v, want %s", *cursors, test.cursors)
+			}
+			if *summary != test.summary {
+				t.Errorf("got summary %+v, want %+v", *summary, test.summary)
+			}
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// QueryPolicy decides which of the GraphQL operations written by the model are sent to the API.
+// Queries are allowed within the depth and complexity limits, mutations only when they are listed
+// and subscriptions never. A zero limit is not enforced.
+type QueryPolicy struct {
+	// MaxDepth is the deepest nesting of fields in an operation.
+	MaxDepth int `json:"maxDepth"`
+	// MaxComplexity is the most fields an operation may return. The fields below a field with a
+	// first argument count once for every item it asks for.
+	MaxComplexity int `json:"maxComplexity"`
+	// MaxFirst is the most items a field may ask for with its first argument.
+	MaxFirst int `json:"maxFirst"`
+	// AllowedMutations are the mutation fields the model may use. They are only allowed for callers
+	// that are not limited to organizations, as a mutation cannot be limited to them.
+	AllowedMutations []string `json:"allowedMutations"`
+	// Audit records every operation that is denied. Denials are not recorded when it is nil.
+	Audit PolicyAuditor `json:"-"`
+}
+
+var DefaultQueryPolicy = QueryPolicy{
+	MaxDepth:      12,
+	MaxComplexity: 10000,
+	MaxFirst:      500,
+}
+
+// PolicyDenial is the record of an operation the policy denied.
+type PolicyDenial struct {
+	Time      string `json:"time"`
+	Caller    string `json:"caller,omitempty"`
+	TaskID    string `json:"taskId,omitempty"`
+	ContextID string `json:"contextId,omitempty"`
+	Tool      string `json:"tool"`
+	Rule      string `json:"rule"`
+	Reason    string `json:"reason"`
+	Query     string `json:"query"`
+}
+
+type PolicyAuditor interface {
+	Denied(ctx context.Context, denial PolicyDenial)
+}
+
+// JSONPolicyAuditor writes every denial as a line of JSON.
+type JSONPolicyAuditor struct {
+	mu     sync.Mutex
+	Writer io.Writer
+}
+
+func NewJSONPolicyAuditor(writer io.Writer) *JSONPolicyAuditor {
+	return &JSONPolicyAuditor{Writer: writer}
+}
+
+func (a *JSONPolicyAuditor) Denied(ctx context.Context, denial PolicyDenial) {
+	line, err := json.Marshal(denial)
+	if err != nil {
+		fmt.Printf("Query Policy: failed to audit denied operation: %v\n", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.Writer.Write(append(line, '\n')); err != nil {
+		fmt.Printf("Query Policy: failed to audit denied operation: %v\n", err)
+	}
+}
+
+// PolicyError is returned for an operation the policy denied. Rule names the rule that denied it.
+type PolicyError struct {
+	Rule   string
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return "tool call failed. the operation is not allowed and was not executed: " + e.Reason
+}
+
+// Check parses a query written by the model and denies it when it breaks the policy. Denials are
+// audited with the task of the tool call.
+func (p *QueryPolicy) Check(ctx context.Context, tool string, query string) (*ast.QueryDocument, error) {
+	// An operation that cannot be parsed cannot be checked, so it is denied as well.
+	document, parseErr := parser.ParseQuery(&ast.Source{Input: query})
+	if parseErr != nil {
+		p.audit(ctx, tool, query, &PolicyError{Rule: "parse", Reason: parseErr.Error()})
+		return nil, fmt.Errorf("tool call failed. the query could not be parsed: %w", parseErr)
+	}
+
+	scoped := false
+	if invocation, err := InvocationFromContext(ctx); err == nil {
+		scoped = invocation.Organizations != nil
+	}
+	if err := p.check(document, scoped); err != nil {
+		p.audit(ctx, tool, query, err)
+		return nil, err
+	}
+	return document, nil
+}
+
+func (p *QueryPolicy) check(document *ast.QueryDocument, scoped bool) *PolicyError {
+	for _, operation := range document.Operations {
+		switch operation.Operation {
+		case ast.Subscription:
+			return &PolicyError{Rule: "subscription", Reason: "subscriptions cannot be run"}
+		case ast.Mutation:
+			if scoped {
+				return &PolicyError{Rule: "mutation", Reason: "mutations cannot be limited to the caller's organizations, so they cannot be run. only queries can be run"}
+			}
+			for _, name := range rootFieldNames(document, operation.SelectionSet, map[string]bool{}) {
+				if !Contains(p.AllowedMutations, name) {
+					return &PolicyError{Rule: "mutation", Reason: fmt.Sprintf("mutation %s is not allowed. only queries can be run", name)}
+				}
+			}
+		}
+
+		if p.MaxDepth > 0 {
+			if depth := selectionDepth(document, operation.SelectionSet, map[string]bool{}); depth > p.MaxDepth {
+				return &PolicyError{Rule: "depth", Reason: fmt.Sprintf("fields are nested %d deep, which is more than the limit of %d", depth, p.MaxDepth)}
+			}
+		}
+		if p.MaxFirst > 0 {
+			if first := largestFirst(document, operation.SelectionSet, map[string]bool{}); first > p.MaxFirst {
+				return &PolicyError{Rule: "first", Reason: fmt.Sprintf("a field asks for %d items, which is more than the limit of %d. ask for fewer items or paginate", first, p.MaxFirst)}
+			}
+		}
+		if p.MaxComplexity > 0 {
+			if complexity := selectionComplexity(document, operation.SelectionSet, p.MaxComplexity, map[string]bool{}); complexity > p.MaxComplexity {
+				return &PolicyError{Rule: "complexity", Reason: fmt.Sprintf("the operation asks for more than the limit of %d fields. ask for fewer items or fields", p.MaxComplexity)}
+			}
+		}
+	}
+	return nil
+}
+
+func (p *QueryPolicy) audit(ctx context.Context, tool string, query string, err *PolicyError) {
+	fmt.Printf("Query Policy: %s denied by the %s rule: %s\n", tool, err.Rule, err.Reason)
+	if p.Audit == nil {
+		return
+	}
+
+	denial := PolicyDenial{
+		Time:   time.Now().UTC().Format(time.RFC3339),
+		Tool:   tool,
+		Rule:   err.Rule,
+		Reason: err.Reason,
+		Query:  query,
+	}
+	if invocation, invocationErr := InvocationFromContext(ctx); invocationErr == nil {
+		denial.Caller = invocation.CallerID
+		denial.TaskID = invocation.TaskID
+		if invocation.ContextID != nil {
+			denial.ContextID = *invocation.ContextID
+		}
+	}
+	p.Audit.Denied(ctx, denial)
+}
+
+// The walks below follow fragment spreads. A fragment already on the path is not followed again, so
+// that a cycle cannot loop forever.
+
+func rootFieldNames(document *ast.QueryDocument, selections ast.SelectionSet, path map[string]bool) []string {
+	var names []string
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			if s.Name != "__typename" {
+				names = append(names, s.Name)
+			}
+		case *ast.InlineFragment:
+			names = append(names, rootFieldNames(document, s.SelectionSet, path)...)
+		case *ast.FragmentSpread:
+			if fragment := document.Fragments.ForName(s.Name); fragment != nil && !path[s.Name] {
+				path[s.Name] = true
+				names = append(names, rootFieldNames(document, fragment.SelectionSet, path)...)
+				delete(path, s.Name)
+			}
+		}
+	}
+	return names
+}
+
+func selectionDepth(document *ast.QueryDocument, selections ast.SelectionSet, path map[string]bool) int {
+	depth := 0
+	for _, selection := range selections {
+		var candidate int
+		switch s := selection.(type) {
+		case *ast.Field:
+			candidate = 1 + selectionDepth(document, s.SelectionSet, path)
+		case *ast.InlineFragment:
+			candidate = selectionDepth(document, s.SelectionSet, path)
+		case *ast.FragmentSpread:
+			if fragment := document.Fragments.ForName(s.Name); fragment != nil && !path[s.Name] {
+				path[s.Name] = true
+				candidate = selectionDepth(document, fragment.SelectionSet, path)
+				delete(path, s.Name)
+			}
+		}
+		if candidate > depth {
+			depth = candidate
+		}
+	}
+	return depth
+}
+
+func largestFirst(document *ast.QueryDocument, selections ast.SelectionSet, path map[string]bool) int {
+	largest := 0
+	for _, selection := range selections {
+		var candidate int
+		switch s := selection.(type) {
+		case *ast.Field:
+			candidate = largestFirst(document, s.SelectionSet, path)
+			if first, ok := firstArgument(s); ok && first > candidate {
+				candidate = first
+			}
+		case *ast.InlineFragment:
+			candidate = largestFirst(document, s.SelectionSet, path)
+		case *ast.FragmentSpread:
+			if fragment := document.Fragments.ForName(s.Name); fragment != nil && !path[s.Name] {
+				path[s.Name] = true
+				candidate = largestFirst(document, fragment.SelectionSet, path)
+				delete(path, s.Name)
+			}
+		}
+		if candidate > largest {
+			largest = candidate
+		}
+	}
+	return largest
+}
+
+// selectionComplexity counts the fields of a selection set until the count is over the limit. The
+// count saturates rather than overflows, so that a large first argument cannot wrap it below the limit.
+func selectionComplexity(document *ast.QueryDocument, selections ast.SelectionSet, limit int, path map[string]bool) int {
+	complexity := 0
+	for _, selection := range selections {
+		switch s := selection.(type) {
+		case *ast.Field:
+			children := selectionComplexity(document, s.SelectionSet, limit, path)
+			complexity = saturatingAdd(complexity, saturatingAdd(1, saturatingMultiply(fieldMultiplier(s), children)))
+		case *ast.InlineFragment:
+			complexity = saturatingAdd(complexity, selectionComplexity(document, s.SelectionSet, limit, path))
+		case *ast.FragmentSpread:
+			if fragment := document.Fragments.ForName(s.Name); fragment != nil && !path[s.Name] {
+				path[s.Name] = true
+				complexity = saturatingAdd(complexity, selectionComplexity(document, fragment.SelectionSet, limit, path))
+				delete(path, s.Name)
+			}
+		}
+		if complexity > limit {
+			return complexity
+		}
+	}
+	return complexity
+}
+
+// defaultPageSize is the number of items assetSearch returns without a first argument.
+const defaultPageSize = 20
+
+// fieldMultiplier is the number of items a field asks for with its first argument. Without a
+// literal first argument the default page size is assumed for assetSearch and one item otherwise.
+func fieldMultiplier(field *ast.Field) int {
+	if first, ok := firstArgument(field); ok && first > 0 {
+		return first
+	}
+	if field.Name == paginatedField {
+		return defaultPageSize
+	}
+	return 1
+}
+
+// firstArgument is the literal first argument of a field. A number too large for an int is read as
+// the largest int, so that it is over every limit rather than ignored.
+func firstArgument(field *ast.Field) (int, bool) {
+	argument := field.Arguments.ForName("first")
+	if argument == nil || argument.Value.Kind != ast.IntValue {
+		return 0, false
+	}
+	first, err := strconv.Atoi(argument.Value.Raw)
+	if err != nil && !errors.Is(err, strconv.ErrRange) {
+		return 0, false
+	}
+	return first, true
+}
+
+func saturatingAdd(a int, b int) int {
+	if a > math.MaxInt-b {
+		return math.MaxInt
+	}
+	return a + b
+}
+
+func saturatingMultiply(a int, b int) int {
+	if a != 0 && b > math.MaxInt/a {
+		return math.MaxInt
+	}
+	return a * b
+}
@@ -0,0 +1,169 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestQueryPolicy(t *testing.T) {
+	policy := QueryPolicy{
+		MaxDepth:         4,
+		MaxComplexity:    100,
+		MaxFirst:         50,
+		AllowedMutations: []string{"acknowledgeAlert"},
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		scoped bool
+		rule   string
+	}{
+		{
+			name:  "query within the limits",
+			query: `query { assetSearch(first: 10) { totalCount nodes { id name } } }`,
+		},
+		{
+			name:  "fields nested too deep",
+			query: `query { a { b { c { d { e } } } } }`,
+			rule:  "depth",
+		},
+		{
+			name:  "fields nested too deep in a fragment",
+			query: `query { a { ...B } } fragment B on T { b { c { d { e } } } }`,
+			rule:  "depth",
+		},
+		{
+			name:  "too many items",
+			query: `query { assetSearch(first: 30) { nodes { id name tags { name } } } }`,
+			rule:  "complexity",
+		},
+		{
+			name:  "too many items in a fragment",
+			query: `query { assetSearch(first: 30) { ...Nodes } } fragment Nodes on AssetConnection { nodes { id name tags { name } } }`,
+			rule:  "complexity",
+		},
+		{
+			name:  "too many items in aliases of the same field",
+			query: `query { a: assetSearch(first: 30) { nodes { id } } b: assetSearch(first: 30) { nodes { id } } c: assetSearch(first: 30) { nodes { id } } d: assetSearch(first: 30) { nodes { id } } }`,
+			rule:  "complexity",
+		},
+		{
+			name:  "first over the limit",
+			query: `query { assetSearch(first: 51) { totalCount } }`,
+			rule:  "first",
+		},
+		{
+			name:  "first that overflows the complexity",
+			query: `query { assetSearch(first: 4611686018427387904) { totalCount nodes { id } } }`,
+			rule:  "first",
+		},
+		{
+			name:  "first too large for an int",
+			query: `query { assetSearch(first: 99999999999999999999999) { totalCount } }`,
+			rule:  "first",
+		},
+		{
+			name:  "first over the limit in a fragment",
+			query: `query { ...Search } fragment Search on Query { assetSearch(first: 1000) { totalCount } }`,
+			rule:  "first",
+		},
+		{
+			name:  "fragment cycle",
+			query: `query { a { ...A } } fragment A on T { b { ...A } }`,
+		},
+		{
+			name:  "subscription",
+			query: `subscription { assetChanged { id } }`,
+			rule:  "subscription",
+		},
+		{
+			name:  "allowed mutation",
+			query: `mutation { acknowledgeAlert(id: "1") { id } }`,
+		},
+		{
+			name:  "allowed mutation behind an alias",
+			query: `mutation { ack: acknowledgeAlert(id: "1") { id } }`,
+		},
+		{
+			name:  "mutation that is not allowed",
+			query: `mutation { ack: acknowledgeAlert(id: "1") { id } deleteAsset(id: "1") { id } }`,
+			rule:  "mutation",
+		},
+		{
+			name:  "mutation that is not allowed in a fragment",
+			query: `mutation { ...Delete } fragment Delete on Mutation { deleteAsset(id: "1") { id } }`,
+			rule:  "mutation",
+		},
+		{
+			name:   "allowed mutation for a caller limited to organizations",
+			query:  `mutation { acknowledgeAlert(id: "1") { id } }`,
+			scoped: true,
+			rule:   "mutation",
+		},
+		{
+			name:  "query that cannot be parsed",
+			query: `query { assetSearch(first: 10) {`,
+			rule:  "parse",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var audit bytes.Buffer
+			checked := policy
+			checked.Audit = NewJSONPolicyAuditor(&audit)
+
+			invocation := &Invocation{TaskID: "task", CallerID: "caller"}
+			if test.scoped {
+				invocation.Organizations = []string{"org"}
+			}
+			_, err := checked.Check(WithInvocation(context.Background(), invocation), "execute_query", test.query)
+
+			if test.rule == "" {
+				if err != nil {
+					t.Fatalf("got %v, want the query to be allowed", err)
+				}
+				if audit.Len() != 0 {
+					t.Errorf("audited %s for an allowed query", audit.String())
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("got no error, want the %s rule to deny the query", test.rule)
+			}
+			var policyErr *PolicyError
+			if errors.As(err, &policyErr) && policyErr.Rule != test.rule {
+				t.Errorf("denied by the %s rule, want the %s rule", policyErr.Rule, test.rule)
+			}
+
+			var denial PolicyDenial
+			if err := json.Unmarshal(audit.Bytes(), &denial); err != nil {
+				t.Fatalf("denial was not audited: %v", err)
+			}
+			if denial.Rule != test.rule || denial.Caller != "caller" || denial.TaskID != "task" || denial.Query != test.query {
+				t.Errorf("audited %+v", denial)
+			}
+		})
+	}
+}
+
+func TestQueryPolicyComplexityDoesNotOverflow(t *testing.T) {
+	// Without a limit on first, the complexity must still be over the limit however large first is.
+	policy := QueryPolicy{MaxComplexity: DefaultQueryPolicy.MaxComplexity}
+
+	for _, query := range []string{
+		`query { assetSearch(first: 4611686018427387904) { totalCount nodes { id } } }`,
+		`query { assetSearch(first: 9223372036854775807) { nodes { id } } }`,
+		`query { assetSearch(first: 99999999999999999999999) { nodes { id } } }`,
+		`query { a(first: 4294967296) { b(first: 4294967296) { c(first: 4294967296) { id } } } }`,
+	} {
+		if _, err := policy.Check(context.Background(), "execute_query", query); err == nil {
+			t.Errorf("%s was allowed, want it denied by the complexity rule", query)
+		}
+	}
+}
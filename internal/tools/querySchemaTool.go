@@ -1,61 +1,90 @@
 package tools
 
 import (
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"context"
+	"fmt"
+	"fusion/internal/llm"
 	"strings"
 )
 
 type QuerySchemaTool struct {
 	Name        string
 	Description string
+	Schemas     *SchemaProvider
 }
 
-var querySchemaTool = &QuerySchemaTool{
-	Name:        "query_schema",
-	Description: "Returns a partial GraphQL Schema that can be used to construct queries and mutations for an API that supports searching for managed assets and returning details regarding their operating systems, hardware and more.",
+func NewQuerySchemaTool(schemas *SchemaProvider) *QuerySchemaTool {
+	return &QuerySchemaTool{
+		Name:        "query_schema",
+		Description: "Returns a partial GraphQL Schema that can be used to construct read-only queries for an API that supports searching for managed assets and returning details regarding their operating systems, hardware and more.",
+		Schemas:     schemas,
+	}
 }
 
-func NewQuerySchemaTool() *QuerySchemaTool {
-	return querySchemaTool
-}
+func (t *QuerySchemaTool) GenerateToolSchema() llm.ToolSpec {
 
-func (t *QuerySchemaTool) GenerateToolSchema() *types.ToolMemberToolSpec {
-
-	return &types.ToolMemberToolSpec{
-		Value: types.ToolSpecification{
-			Name:        aws.String(t.Name),
-			Description: aws.String(t.Description),
-			InputSchema: &types.ToolInputSchemaMemberJson{
-				Value: document.NewLazyDocument(map[string]interface{}{
-					"type":       "object",
-					"properties": map[string]interface{}{},
-					"required":   []interface{}{},
-				}),
+	return llm.ToolSpec{
+		Name:        t.Name,
+		Description: t.Description,
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"types": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Optional names of the types to return, e.g. Asset. Types reachable from them and the query fields leading to them are included.",
+				},
+				"fields": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Optional field names, either plain (memoryTotalSizeGB) or qualified with their type (Asset.systemInfo).",
+				},
+				"keywords": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Optional keywords matched against type and field names and descriptions, e.g. memory or operating system.",
+				},
 			},
+			"required": []interface{}{},
 		},
 	}
 }
 
-func (t *QuerySchemaTool) Call(toolCall *types.ContentBlockMemberToolUse) (*types.Message, error) {
-
-	schema := strings.TrimSpace(AssetsSchema)
-	content := document.NewLazyDocument(map[string]interface{}{"schema": schema})
-
-	return &types.Message{
-		Role: types.ConversationRoleUser,
-		Content: []types.ContentBlock{
-			&types.ContentBlockMemberToolResult{
-				Value: types.ToolResultBlock{
-					Content: []types.ToolResultContentBlock{
-						&types.ToolResultContentBlockMemberJson{
-							Value: content,
-						},
-					},
-					ToolUseId: toolCall.Value.ToolUseId,
-				},
-			},
-		},
+func (t *QuerySchemaTool) Call(ctx context.Context, toolCall *llm.ToolUseBlock) (*llm.ToolResultBlock, error) {
+
+	invocation, err := InvocationFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := t.Schemas.Schema(ctx, invocation)
+	if err != nil {
+		return nil, err
+	}
+
+	var request schemaSliceRequest
+	if request.Types, err = stringListParameter(toolCall, "types"); err != nil {
+		return nil, err
+	}
+	if request.Fields, err = stringListParameter(toolCall, "fields"); err != nil {
+		return nil, err
+	}
+	if request.Keywords, err = stringListParameter(toolCall, "keywords"); err != nil {
+		return nil, err
+	}
+
+	slice := sliceSchema(schema.AST, request)
+	sdl := slice.SDL()
+
+	content := sdl
+	if len(slice.unknown) > 0 {
+		content = fmt.Sprintf("# No types or fields matched: %s\n%s", strings.Join(slice.unknown, ", "), sdl)
+	}
+
+	fmt.Printf("Query Schema: returned %d types from the %s schema, approximately %d tokens\n", len(slice.types), schema.Source, EstimateTokens(content))
+
+	return &llm.ToolResultBlock{
+		ToolUseID: toolCall.ID,
+		Text:      content,
 	}, nil
 }
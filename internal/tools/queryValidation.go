@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"regexp"
+)
+
+type queryDiagnostic struct {
+	Message     string   `json:"message"`
+	Rule        string   `json:"rule,omitempty"`
+	Line        int      `json:"line,omitempty"`
+	Column      int      `json:"column,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+type QueryValidationError struct {
+	Diagnostics []queryDiagnostic
+}
+
+func (e *QueryValidationError) Error() string {
+	diagnostics, err := json.Marshal(map[string]interface{}{"diagnostics": e.Diagnostics})
+	if err != nil {
+		return fmt.Sprintf("query is not valid against the asset schema and was not executed: %d problems found", len(e.Diagnostics))
+	}
+	return fmt.Sprintf("query is not valid against the asset schema and was not executed. fix the problems below and try again: %s", diagnostics)
+}
+
+var (
+	didYouMeanPattern = regexp.MustCompile(`Did you mean (.*)\?`)
+	quotedNamePattern = regexp.MustCompile(`"([^"]+)"`)
+)
+
+// validateQuery parses and validates a query against the schema without executing it.
+func validateQuery(schema *ast.Schema, query string) (*ast.QueryDocument, error) {
+	document, errs := gqlparser.LoadQuery(schema, query)
+	if len(errs) == 0 {
+		return document, nil
+	}
+
+	return nil, &QueryValidationError{Diagnostics: diagnosticsFromErrors(errs)}
+}
+
+func diagnosticsFromErrors(errs gqlerror.List) []queryDiagnostic {
+	diagnostics := make([]queryDiagnostic, 0, len(errs))
+	for _, err := range errs {
+		diagnostic := queryDiagnostic{
+			Message: err.Message,
+			Rule:    err.Rule,
+		}
+
+		if len(err.Locations) > 0 {
+			diagnostic.Line = err.Locations[0].Line
+			diagnostic.Column = err.Locations[0].Column
+		}
+
+		if match := didYouMeanPattern.FindStringSubmatch(err.Message); match != nil {
+			for _, name := range quotedNamePattern.FindAllStringSubmatch(match[1], -1) {
+				diagnostic.Suggestions = append(diagnostic.Suggestions, name[1])
+			}
+		}
+
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	return diagnostics
+}
@@ -0,0 +1,180 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"fusion/internal/llm"
+	"sync"
+)
+
+type Metadata struct {
+	DisplayName string
+	Tags        []string
+}
+
+type Registration struct {
+	Name     string
+	Schema   llm.ToolSpec
+	Tool     Tool
+	Metadata Metadata
+}
+
+type Registry struct {
+	// MaxParallel is the most tool calls of one model turn that run at the same time. All of them
+	// run at once when it is zero.
+	MaxParallel int
+
+	mu            sync.RWMutex
+	registrations map[string]Registration
+	names         []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		registrations: map[string]Registration{},
+	}
+}
+
+func (r *Registry) Register(tool Tool, metadata Metadata) error {
+	if tool == nil {
+		return errors.New("tool registration failed. tool must not be nil")
+	}
+
+	schema := tool.GenerateToolSchema()
+	if schema.Name == "" {
+		return errors.New("tool registration failed. tool schema has no name")
+	}
+	name := schema.Name
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.registrations[name]; exists {
+		return fmt.Errorf("tool registration failed. tool %s is already registered", name)
+	}
+
+	r.registrations[name] = Registration{
+		Name:     name,
+		Schema:   schema,
+		Tool:     tool,
+		Metadata: metadata,
+	}
+	r.names = append(r.names, name)
+
+	return nil
+}
+
+func (r *Registry) MustRegister(tool Tool, metadata Metadata) {
+	if err := r.Register(tool, metadata); err != nil {
+		panic(err)
+	}
+}
+
+func (r *Registry) Lookup(name string) (Registration, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	registration, ok := r.registrations[name]
+	return registration, ok
+}
+
+// Registrations returns every registered tool in registration order.
+func (r *Registry) Registrations() []Registration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	registrations := make([]Registration, 0, len(r.names))
+	for _, name := range r.names {
+		registrations = append(registrations, r.registrations[name])
+	}
+	return registrations
+}
+
+func (r *Registry) ToolSpecs() []llm.ToolSpec {
+	registrations := r.Registrations()
+
+	specs := make([]llm.ToolSpec, 0, len(registrations))
+	for _, registration := range registrations {
+		specs = append(specs, registration.Schema)
+	}
+	return specs
+}
+
+// HandleToolUse appends the model's message to the conversation, calls every tool it asked for and
+// appends the results as one message, in the order the tools were asked for. The calls run at the
+// same time, at most MaxParallel at once.
+func (r *Registry) HandleToolUse(ctx context.Context, message llm.Message, messages *[]llm.Message) error {
+	if message.Role != llm.RoleAssistant {
+		return fmt.Errorf("handle tool use failed. unexpected message role %q", message.Role)
+	}
+	*messages = append(*messages, message)
+
+	var toolUses []*llm.ToolUseBlock
+	for _, item := range message.Content {
+		switch contentBlock := item.(type) {
+		case *llm.ReasoningBlock:
+			fmt.Printf("Handle Tool Use: Reasoning: %s\n", contentBlock.Text)
+		case *llm.TextBlock:
+			fmt.Printf("Handle Tool Use: Text: %s\n", contentBlock.Text)
+		case *llm.ToolUseBlock:
+			toolUses = append(toolUses, contentBlock)
+		}
+	}
+	if len(toolUses) == 0 {
+		return nil
+	}
+
+	limit := r.MaxParallel
+	if limit <= 0 || limit > len(toolUses) {
+		limit = len(toolUses)
+	}
+	slots := make(chan struct{}, limit)
+
+	results := make([]llm.ContentBlock, len(toolUses))
+	var wg sync.WaitGroup
+	for i, toolUse := range toolUses {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			results[i] = r.callTool(ctx, toolUse)
+		}()
+	}
+	wg.Wait()
+
+	*messages = append(*messages, llm.Message{
+		Role:    llm.RoleUser,
+		Content: results,
+	})
+
+	return nil
+}
+
+// callTool calls the tool a tool use asks for. A failure is handed back to the model as the result
+// of that call, so that the model can correct its input and retry.
+func (r *Registry) callTool(ctx context.Context, toolUse *llm.ToolUseBlock) (result *llm.ToolResultBlock) {
+	name := toolUse.Name
+	fmt.Printf("Handle Tool Use: Tool Use: %s\n", name)
+
+	registration, ok := r.Lookup(name)
+	if !ok {
+		fmt.Printf("Unknown tool requested by the model: %s\n", name)
+		return errorResult(toolUse, fmt.Errorf("unknown tool %q. use one of the tools provided in the tool configuration", name))
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			fmt.Printf("Tool %s panicked: %v\n", name, recovered)
+			result = errorResult(toolUse, fmt.Errorf("tool call failed. %v", recovered))
+		}
+	}()
+
+	result, err := registration.Tool.Call(ctx, toolUse)
+	if err != nil {
+		fmt.Printf("Error invoking tool %s: %v\n", name, err)
+		return errorResult(toolUse, err)
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// ResultStore keeps tool results that were too large to give to the model in full, so that the
+// model can read the rest with the fetch_result tool. A result is kept for the caller scope of the
+// task it belongs to, and is only given back to that scope. Get reports false when the handle is
+// unknown, belongs to another scope or has expired.
+type ResultStore interface {
+	Put(ctx context.Context, scope string, content string) (string, error)
+	Get(ctx context.Context, scope string, handle string) (string, bool, error)
+}
+
+type RedisResultStore struct {
+	Client *redis.Client
+	TTL    time.Duration
+}
+
+const redisResultPrefix = "result:"
+
+func NewRedisResultStore(client *redis.Client, ttl time.Duration) *RedisResultStore {
+	return &RedisResultStore{Client: client, TTL: ttl}
+}
+
+func (s *RedisResultStore) Put(ctx context.Context, scope string, content string) (string, error) {
+	handle, err := newResultHandle()
+	if err != nil {
+		return "", err
+	}
+	if err := s.Client.Set(ctx, resultKey(scope, handle), content, s.TTL).Err(); err != nil {
+		return "", fmt.Errorf("failed to store tool result: %w", err)
+	}
+	return handle, nil
+}
+
+func (s *RedisResultStore) Get(ctx context.Context, scope string, handle string) (string, bool, error) {
+	content, err := s.Client.Get(ctx, resultKey(scope, handle)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read tool result: %w", err)
+	}
+	return content, true, nil
+}
+
+func resultKey(scope string, handle string) string {
+	return redisResultPrefix + scope + ":" + handle
+}
+
+func newResultHandle() (string, error) {
+	var id [12]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return "", fmt.Errorf("failed to create result handle: %w", err)
+	}
+	return "res-" + hex.EncodeToString(id[:]), nil
+}
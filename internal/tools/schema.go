@@ -0,0 +1,259 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"sort"
+	"strings"
+)
+
+type introspectionResponse struct {
+	Data struct {
+		Schema introspectionSchema `json:"__schema"`
+	} `json:"data"`
+}
+
+type introspectionSchema struct {
+	QueryType        *introspectionTypeName `json:"queryType"`
+	MutationType     *introspectionTypeName `json:"mutationType"`
+	SubscriptionType *introspectionTypeName `json:"subscriptionType"`
+	Types            []introspectionType    `json:"types"`
+}
+
+type introspectionTypeName struct {
+	Name string `json:"name"`
+}
+
+type introspectionType struct {
+	Kind          string                    `json:"kind"`
+	Name          string                    `json:"name"`
+	Description   *string                   `json:"description"`
+	Fields        []introspectionField      `json:"fields"`
+	InputFields   []introspectionInputValue `json:"inputFields"`
+	Interfaces    []introspectionTypeRef    `json:"interfaces"`
+	EnumValues    []introspectionEnumValue  `json:"enumValues"`
+	PossibleTypes []introspectionTypeRef    `json:"possibleTypes"`
+}
+
+type introspectionField struct {
+	Name              string                    `json:"name"`
+	Description       *string                   `json:"description"`
+	Args              []introspectionInputValue `json:"args"`
+	Type              introspectionTypeRef      `json:"type"`
+	IsDeprecated      bool                      `json:"isDeprecated"`
+	DeprecationReason *string                   `json:"deprecationReason"`
+}
+
+type introspectionInputValue struct {
+	Name         string               `json:"name"`
+	Description  *string              `json:"description"`
+	Type         introspectionTypeRef `json:"type"`
+	DefaultValue *string              `json:"defaultValue"`
+}
+
+type introspectionEnumValue struct {
+	Name              string  `json:"name"`
+	Description       *string `json:"description"`
+	IsDeprecated      bool    `json:"isDeprecated"`
+	DeprecationReason *string `json:"deprecationReason"`
+}
+
+type introspectionTypeRef struct {
+	Kind   string                `json:"kind"`
+	Name   *string               `json:"name"`
+	OfType *introspectionTypeRef `json:"ofType"`
+}
+
+var builtInScalars = map[string]bool{
+	"String":  true,
+	"Int":     true,
+	"Float":   true,
+	"Boolean": true,
+	"ID":      true,
+}
+
+func parseIntrospection(data []byte) (*introspectionSchema, error) {
+	var response introspectionResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse schema introspection: %w", err)
+	}
+
+	if response.Data.Schema.QueryType == nil || len(response.Data.Schema.Types) == 0 {
+		return nil, fmt.Errorf("failed to parse schema introspection: no query type or types found")
+	}
+
+	return &response.Data.Schema, nil
+}
+
+func buildSchema(introspection *introspectionSchema) (*ast.Schema, error) {
+	sdl := introspectionToSDL(introspection)
+
+	schema, err := gqlparser.LoadSchema(&ast.Source{Name: "assets.graphqls", Input: sdl})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build schema from introspection: %w", err)
+	}
+
+	return schema, nil
+}
+
+// introspectionToSDL renders an introspection result as SDL. Types that are referenced but not
+// described by the introspection result are declared as scalars so that a partial schema still loads.
+func introspectionToSDL(introspection *introspectionSchema) string {
+	var sdl strings.Builder
+
+	defined := map[string]bool{}
+	referenced := map[string]bool{}
+
+	for _, t := range introspection.Types {
+		if strings.HasPrefix(t.Name, "__") || builtInScalars[t.Name] {
+			continue
+		}
+		defined[t.Name] = true
+
+		writeDescription(&sdl, t.Description, "")
+
+		switch t.Kind {
+		case "SCALAR":
+			fmt.Fprintf(&sdl, "scalar %s\n\n", t.Name)
+
+		case "OBJECT", "INTERFACE":
+			keyword := "type"
+			if t.Kind == "INTERFACE" {
+				keyword = "interface"
+			}
+			fmt.Fprintf(&sdl, "%s %s", keyword, t.Name)
+
+			if len(t.Interfaces) > 0 {
+				names := make([]string, 0, len(t.Interfaces))
+				for _, i := range t.Interfaces {
+					names = append(names, typeRefName(i))
+					referenced[typeRefName(i)] = true
+				}
+				fmt.Fprintf(&sdl, " implements %s", strings.Join(names, " & "))
+			}
+
+			sdl.WriteString(" {\n")
+			for _, field := range t.Fields {
+				writeDescription(&sdl, field.Description, "  ")
+				fmt.Fprintf(&sdl, "  %s", field.Name)
+				if len(field.Args) > 0 {
+					args := make([]string, 0, len(field.Args))
+					for _, arg := range field.Args {
+						args = append(args, inputValueSDL(arg))
+						referenced[typeRefName(arg.Type)] = true
+					}
+					fmt.Fprintf(&sdl, "(%s)", strings.Join(args, ", "))
+				}
+				fmt.Fprintf(&sdl, ": %s", typeRefSDL(field.Type))
+				writeDeprecation(&sdl, field.IsDeprecated, field.DeprecationReason)
+				sdl.WriteString("\n")
+				referenced[typeRefName(field.Type)] = true
+			}
+			sdl.WriteString("}\n\n")
+
+		case "UNION":
+			names := make([]string, 0, len(t.PossibleTypes))
+			for _, possibleType := range t.PossibleTypes {
+				names = append(names, typeRefName(possibleType))
+				referenced[typeRefName(possibleType)] = true
+			}
+			fmt.Fprintf(&sdl, "union %s = %s\n\n", t.Name, strings.Join(names, " | "))
+
+		case "ENUM":
+			fmt.Fprintf(&sdl, "enum %s {\n", t.Name)
+			for _, value := range t.EnumValues {
+				writeDescription(&sdl, value.Description, "  ")
+				fmt.Fprintf(&sdl, "  %s", value.Name)
+				writeDeprecation(&sdl, value.IsDeprecated, value.DeprecationReason)
+				sdl.WriteString("\n")
+			}
+			sdl.WriteString("}\n\n")
+
+		case "INPUT_OBJECT":
+			fmt.Fprintf(&sdl, "input %s {\n", t.Name)
+			for _, field := range t.InputFields {
+				writeDescription(&sdl, field.Description, "  ")
+				fmt.Fprintf(&sdl, "  %s\n", inputValueSDL(field))
+				referenced[typeRefName(field.Type)] = true
+			}
+			sdl.WriteString("}\n\n")
+		}
+	}
+
+	var missing []string
+	for name := range referenced {
+		if name != "" && !defined[name] && !builtInScalars[name] && !strings.HasPrefix(name, "__") {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		fmt.Fprintf(&sdl, "scalar %s\n\n", name)
+	}
+
+	sdl.WriteString("schema {\n")
+	fmt.Fprintf(&sdl, "  query: %s\n", introspection.QueryType.Name)
+	if introspection.MutationType != nil && introspection.MutationType.Name != "" {
+		fmt.Fprintf(&sdl, "  mutation: %s\n", introspection.MutationType.Name)
+	}
+	if introspection.SubscriptionType != nil && introspection.SubscriptionType.Name != "" {
+		fmt.Fprintf(&sdl, "  subscription: %s\n", introspection.SubscriptionType.Name)
+	}
+	sdl.WriteString("}\n")
+
+	return sdl.String()
+}
+
+func inputValueSDL(value introspectionInputValue) string {
+	sdl := fmt.Sprintf("%s: %s", value.Name, typeRefSDL(value.Type))
+	if value.DefaultValue != nil {
+		sdl += " = " + *value.DefaultValue
+	}
+	return sdl
+}
+
+func typeRefSDL(ref introspectionTypeRef) string {
+	switch ref.Kind {
+	case "NON_NULL":
+		if ref.OfType != nil {
+			return typeRefSDL(*ref.OfType) + "!"
+		}
+	case "LIST":
+		if ref.OfType != nil {
+			return "[" + typeRefSDL(*ref.OfType) + "]"
+		}
+	}
+	return typeRefName(ref)
+}
+
+func typeRefName(ref introspectionTypeRef) string {
+	if ref.OfType != nil && (ref.Kind == "NON_NULL" || ref.Kind == "LIST") {
+		return typeRefName(*ref.OfType)
+	}
+	if ref.Name == nil {
+		return ""
+	}
+	return *ref.Name
+}
+
+func writeDescription(sdl *strings.Builder, description *string, indent string) {
+	if description == nil || strings.TrimSpace(*description) == "" {
+		return
+	}
+	escaped := strings.ReplaceAll(*description, `"""`, `\"""`)
+	fmt.Fprintf(sdl, "%s\"\"\"%s\"\"\"\n", indent, escaped)
+}
+
+func writeDeprecation(sdl *strings.Builder, isDeprecated bool, reason *string) {
+	if !isDeprecated {
+		return
+	}
+	if reason == nil {
+		sdl.WriteString(" @deprecated")
+		return
+	}
+	reasonJSON, _ := json.Marshal(*reason)
+	fmt.Fprintf(sdl, " @deprecated(reason: %s)", reasonJSON)
+}
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SchemaCache stores introspection results between agent restarts. Get reports false when the key
+// is missing or has expired.
+type SchemaCache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+type FileSchemaCache struct {
+	Dir string
+}
+
+type fileSchemaCacheEntry struct {
+	ExpiresAt time.Time       `json:"expiresAt"`
+	Value     json.RawMessage `json:"value"`
+}
+
+func NewFileSchemaCache(dir string) *FileSchemaCache {
+	return &FileSchemaCache{Dir: dir}
+}
+
+func (c *FileSchemaCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := os.ReadFile(c.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read schema cache: %w", err)
+	}
+
+	var entry fileSchemaCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false, fmt.Errorf("failed to parse schema cache: %w", err)
+	}
+
+	if time.Now().After(entry.ExpiresAt) {
+		return nil, false, nil
+	}
+
+	return entry.Value, true, nil
+}
+
+func (c *FileSchemaCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create schema cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(fileSchemaCacheEntry{
+		ExpiresAt: time.Now().Add(ttl),
+		Value:     value,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to serialise schema cache entry: %w", err)
+	}
+
+	// Write to a temporary file first so that a concurrent reader never sees a partial entry.
+	temp, err := os.CreateTemp(c.Dir, "schema-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write schema cache: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write schema cache: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write schema cache: %w", err)
+	}
+
+	if err := os.Rename(temp.Name(), c.path(key)); err != nil {
+		return fmt.Errorf("failed to write schema cache: %w", err)
+	}
+
+	return nil
+}
+
+func (c *FileSchemaCache) path(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, "schema-"+hex.EncodeToString(hash[:8])+".json")
+}
+
+type RedisSchemaCache struct {
+	Client *redis.Client
+}
+
+const redisSchemaCachePrefix = "schema:"
+
+func NewRedisSchemaCache(client *redis.Client) *RedisSchemaCache {
+	return &RedisSchemaCache{Client: client}
+}
+
+func (c *RedisSchemaCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	data, err := c.Client.Get(ctx, redisSchemaCachePrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read schema cache: %w", err)
+	}
+	return data, true, nil
+}
+
+func (c *RedisSchemaCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := c.Client.Set(ctx, redisSchemaCachePrefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to write schema cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"fmt"
+	"github.com/vektah/gqlparser/v2/ast"
+	"sort"
+	"strings"
+)
+
+// DiffSchemas lists the type, field, argument and enum value changes between two schemas. Lines
+// start with "+" for additions, "-" for removals and "~" for changed types.
+func DiffSchemas(previous *ast.Schema, current *ast.Schema) []string {
+	var changes []string
+
+	for _, name := range definitionNames(previous, current) {
+		before, after := previous.Types[name], current.Types[name]
+
+		switch {
+		case before == nil:
+			changes = append(changes, fmt.Sprintf("+ %s %s", kindKeyword(after.Kind), name))
+			continue
+		case after == nil:
+			changes = append(changes, fmt.Sprintf("- %s %s", kindKeyword(before.Kind), name))
+			continue
+		case before.Kind != after.Kind:
+			changes = append(changes, fmt.Sprintf("~ %s: %s -> %s", name, kindKeyword(before.Kind), kindKeyword(after.Kind)))
+			continue
+		}
+
+		changes = append(changes, diffFields(name, before.Fields, after.Fields)...)
+		changes = append(changes, diffEnumValues(name, before.EnumValues, after.EnumValues)...)
+	}
+
+	return changes
+}
+
+func diffFields(typeName string, before ast.FieldList, after ast.FieldList) []string {
+	var changes []string
+
+	for _, field := range before {
+		if strings.HasPrefix(field.Name, "__") {
+			continue
+		}
+		updated := after.ForName(field.Name)
+		if updated == nil {
+			changes = append(changes, fmt.Sprintf("- field %s.%s: %s", typeName, field.Name, field.Type))
+			continue
+		}
+		if field.Type.String() != updated.Type.String() {
+			changes = append(changes, fmt.Sprintf("~ field %s.%s: %s -> %s", typeName, field.Name, field.Type, updated.Type))
+		}
+
+		for _, arg := range field.Arguments {
+			updatedArg := updated.Arguments.ForName(arg.Name)
+			if updatedArg == nil {
+				changes = append(changes, fmt.Sprintf("- argument %s.%s(%s: %s)", typeName, field.Name, arg.Name, arg.Type))
+			} else if arg.Type.String() != updatedArg.Type.String() {
+				changes = append(changes, fmt.Sprintf("~ argument %s.%s(%s): %s -> %s", typeName, field.Name, arg.Name, arg.Type, updatedArg.Type))
+			}
+		}
+		for _, arg := range updated.Arguments {
+			if field.Arguments.ForName(arg.Name) == nil {
+				changes = append(changes, fmt.Sprintf("+ argument %s.%s(%s: %s)", typeName, field.Name, arg.Name, arg.Type))
+			}
+		}
+	}
+
+	for _, field := range after {
+		if !strings.HasPrefix(field.Name, "__") && before.ForName(field.Name) == nil {
+			changes = append(changes, fmt.Sprintf("+ field %s.%s: %s", typeName, field.Name, field.Type))
+		}
+	}
+
+	return changes
+}
+
+func diffEnumValues(typeName string, before ast.EnumValueList, after ast.EnumValueList) []string {
+	var changes []string
+
+	for _, value := range before {
+		if after.ForName(value.Name) == nil {
+			changes = append(changes, fmt.Sprintf("- enum value %s.%s", typeName, value.Name))
+		}
+	}
+	for _, value := range after {
+		if before.ForName(value.Name) == nil {
+			changes = append(changes, fmt.Sprintf("+ enum value %s.%s", typeName, value.Name))
+		}
+	}
+
+	return changes
+}
+
+func definitionNames(schemas ...*ast.Schema) []string {
+	seen := map[string]bool{}
+	var names []string
+
+	for _, schema := range schemas {
+		for name, definition := range schema.Types {
+			if seen[name] || definition.BuiltIn {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+func kindKeyword(kind ast.DefinitionKind) string {
+	switch kind {
+	case ast.Object:
+		return "type"
+	case ast.InputObject:
+		return "input"
+	default:
+		return strings.ToLower(string(kind))
+	}
+}
@@ -0,0 +1,211 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/vektah/gqlparser/v2/ast"
+	"golang.org/x/sync/singleflight"
+	"sync"
+	"time"
+)
+
+const (
+	SchemaSourceLive     = "live"
+	SchemaSourceCache    = "cache"
+	SchemaSourceEmbedded = "embedded"
+)
+
+// embeddedSchemaRetryInterval limits how long the embedded snapshot is used before introspection
+// of the live schema is attempted again.
+const embeddedSchemaRetryInterval = time.Minute
+
+//go:embed introspection.gql
+var introspectionQuery string
+
+type Schema struct {
+	Introspection []byte
+	AST           *ast.Schema
+	Source        string
+}
+
+// SchemaProvider keeps a schema for every caller scope, as the API may show each caller only part of
+// it. A schema is introspected with the token of a caller in the scope.
+type SchemaProvider struct {
+	client *GraphQLClient
+	cache  SchemaCache
+	ttl    time.Duration
+	key    string
+
+	// fetches lets the callers of a scope that find no current schema share a single fetch.
+	fetches singleflight.Group
+	mu      sync.Mutex
+	schemas map[string]scopedSchema
+}
+
+type scopedSchema struct {
+	schema    *Schema
+	expiresAt time.Time
+}
+
+var (
+	embeddedSchemaOnce sync.Once
+	embeddedSchema     *Schema
+	embeddedSchemaErr  error
+)
+
+// NewSchemaProvider returns a provider that introspects the live asset API and keeps the result for
+// the given TTL, in memory and in the cache when one is given.
+func NewSchemaProvider(client *GraphQLClient, cache SchemaCache, ttl time.Duration) *SchemaProvider {
+	return &SchemaProvider{
+		client: client,
+		cache:  cache,
+		ttl:    ttl,
+		key:    "assets:" + client.Endpoint,
+	}
+}
+
+// Schema returns the schema of the caller of an invocation.
+func (p *SchemaProvider) Schema(ctx context.Context, invocation *Invocation) (*Schema, error) {
+	key := p.key + ":" + invocation.Scope()
+	if schema, ok := p.cached(key); ok {
+		return schema, nil
+	}
+
+	// The fetch is detached from the context of the caller that started it, so that a caller giving up
+	// does not fail the others waiting for it. The client timeout still bounds it.
+	fetched := p.fetches.DoChan(key, func() (interface{}, error) {
+		return p.fetch(context.WithoutCancel(ctx), key, invocation.Token)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case result := <-fetched:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.(*Schema), nil
+	}
+}
+
+func (p *SchemaProvider) cached(key string) (*Schema, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cached, ok := p.schemas[key]; ok && time.Now().Before(cached.expiresAt) {
+		return cached.schema, true
+	}
+	return nil, false
+}
+
+// store keeps the schema of a scope, and drops the schemas of scopes that have expired.
+func (p *SchemaProvider) store(key string, schema *Schema, ttl time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if p.schemas == nil {
+		p.schemas = map[string]scopedSchema{}
+	}
+	for other, cached := range p.schemas {
+		if now.After(cached.expiresAt) {
+			delete(p.schemas, other)
+		}
+	}
+	p.schemas[key] = scopedSchema{schema: schema, expiresAt: now.Add(ttl)}
+}
+
+// fetch reads the schema of a scope from the cache, or introspects the live API, and falls back to
+// the embedded snapshot when neither works.
+func (p *SchemaProvider) fetch(ctx context.Context, key string, token string) (*Schema, error) {
+	// A fetch that finished while this one was waiting to start has already stored the schema.
+	if schema, ok := p.cached(key); ok {
+		return schema, nil
+	}
+
+	if p.cache != nil {
+		data, ok, err := p.cache.Get(ctx, key)
+		if err != nil {
+			fmt.Printf("Schema cache lookup failed: %v\n", err)
+		} else if ok {
+			schema, err := newSchema(data, SchemaSourceCache)
+			if err == nil {
+				p.store(key, schema, p.ttl)
+				return schema, nil
+			}
+			fmt.Printf("Ignoring invalid cached schema: %v\n", err)
+		}
+	}
+
+	schema, err := p.introspect(ctx, key, token)
+	if err == nil {
+		p.store(key, schema, p.ttl)
+		return schema, nil
+	}
+	fmt.Printf("Schema introspection failed, using the embedded snapshot: %v\n", err)
+
+	schema, err = EmbeddedSchema()
+	if err != nil {
+		return nil, err
+	}
+	p.store(key, schema, embeddedSchemaRetryInterval)
+	return schema, nil
+}
+
+func (p *SchemaProvider) introspect(ctx context.Context, key string, token string) (*Schema, error) {
+	data, err := IntrospectSchema(ctx, p.client, token)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := newSchema(data, SchemaSourceLive)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.cache != nil {
+		if err := p.cache.Set(ctx, key, data, p.ttl); err != nil {
+			fmt.Printf("Failed to cache introspected schema: %v\n", err)
+		}
+	}
+
+	return schema, nil
+}
+
+// IntrospectSchema fetches the introspection result of the live asset API.
+func IntrospectSchema(ctx context.Context, client *GraphQLClient, token string) ([]byte, error) {
+	response, err := client.Execute(ctx, token, GraphQLRequest{Query: introspectionQuery, OperationName: "IntrospectionQuery"})
+	if err != nil {
+		return nil, fmt.Errorf("schema introspection failed: %w", err)
+	}
+	return response.Raw, nil
+}
+
+func EmbeddedSchema() (*Schema, error) {
+	embeddedSchemaOnce.Do(func() {
+		embeddedSchema, embeddedSchemaErr = newSchema([]byte(AssetsSchema), SchemaSourceEmbedded)
+	})
+	return embeddedSchema, embeddedSchemaErr
+}
+
+// ParseSchema builds a schema from an introspection result.
+func ParseSchema(data []byte) (*ast.Schema, error) {
+	introspection, err := parseIntrospection(data)
+	if err != nil {
+		return nil, err
+	}
+	return buildSchema(introspection)
+}
+
+func newSchema(data []byte, source string) (*Schema, error) {
+	schema, err := ParseSchema(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Schema{
+		Introspection: data,
+		AST:           schema,
+		Source:        source,
+	}, nil
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"fusion/internal/llm"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// schemaServer answers introspection with the embedded snapshot, or fails it when introspection is
+// disabled, and answers every other query with an asset. It counts the introspections of each token.
+type schemaServer struct {
+	*httptest.Server
+	introspection bool
+
+	mu             sync.Mutex
+	introspections map[string]int
+}
+
+func newSchemaServer(t *testing.T, introspection bool) *schemaServer {
+	server := &schemaServer{introspection: introspection, introspections: map[string]int{}}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request GraphQLRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("invalid GraphQL request: %v", err)
+		}
+		if request.OperationName != "IntrospectionQuery" {
+			fmt.Fprint(w, `{"data":{"asset":{"id":"1"}}}`)
+			return
+		}
+		if !server.introspection {
+			http.Error(w, "introspection disabled", http.StatusServiceUnavailable)
+			return
+		}
+
+		server.mu.Lock()
+		server.introspections[r.Header.Get("Authorization")]++
+		server.mu.Unlock()
+		fmt.Fprint(w, AssetsSchema)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSchemaProviderKeepsASchemaPerCaller(t *testing.T) {
+	server := newSchemaServer(t, true)
+	schemas := NewSchemaProvider(NewGraphQLClient(server.URL), nil, time.Hour)
+
+	callers := []*Invocation{
+		{CallerID: "caller-a", Token: "token-a", Organizations: []string{"org-a"}},
+		{CallerID: "caller-b", Token: "token-b", Organizations: []string{"org-b"}},
+		// A new token of the first caller is in the same scope.
+		{CallerID: "caller-a", Token: "token-a2", Organizations: []string{"org-a"}},
+		// The same caller limited to other organizations is not.
+		{CallerID: "caller-a", Token: "token-a3", Organizations: []string{"org-a", "org-b"}},
+	}
+	for _, invocation := range callers {
+		schema, err := schemas.Schema(context.Background(), invocation)
+		if err != nil {
+			t.Fatalf("failed to get schema of %s: %v", invocation.CallerID, err)
+		}
+		if schema.Source != SchemaSourceLive {
+			t.Errorf("schema of %s is from the %s source, want live", invocation.CallerID, schema.Source)
+		}
+	}
+
+	want := map[string]int{"Bearer token-a": 1, "Bearer token-b": 1, "Bearer token-a3": 1}
+	if fmt.Sprint(server.introspections) != fmt.Sprint(want) {
+		t.Errorf("introspected with %v, want %v", server.introspections, want)
+	}
+}
+
+func TestEmbeddedSchemaHasTheAssetDetailsQuery(t *testing.T) {
+	schema, err := EmbeddedSchema()
+	if err != nil {
+		t.Fatalf("embedded schema is invalid: %v", err)
+	}
+	if _, err := validateQuery(schema.AST, assetDetailsQuery); err != nil {
+		t.Errorf("asset details query does not validate against the embedded schema: %v", err)
+	}
+}
+
+func TestExecuteQueryDoesNotRejectQueriesWithTheEmbeddedSchema(t *testing.T) {
+	server := newSchemaServer(t, false)
+	client := NewGraphQLClient(server.URL)
+	tool := NewExecuteQueryTool(client, NewSchemaProvider(client, nil, time.Hour), DefaultPaginationLimits, &DefaultQueryPolicy)
+
+	// The field is missing from the snapshot, but may be in the live schema.
+	ctx := WithInvocation(context.Background(), &Invocation{TaskID: "task", CallerID: "caller", Token: "token"})
+	result, err := tool.Call(ctx, &llm.ToolUseBlock{ID: "call", Name: tool.Name, Input: map[string]interface{}{
+		"query": `query { asset(id: "1") { id lastSeenAt } }`,
+	}})
+	if err != nil {
+		t.Fatalf("query was rejected: %v", err)
+	}
+	if assets, _ := result.JSON.(map[string]interface{})["assets"].(string); !strings.Contains(assets, `"id":"1"`) {
+		t.Errorf("got result %v, want the asset", result.JSON)
+	}
+}
@@ -0,0 +1,303 @@
+package tools
+
+import (
+	"fmt"
+	"github.com/vektah/gqlparser/v2/ast"
+	"sort"
+	"strings"
+)
+
+type schemaSliceRequest struct {
+	Types    []string
+	Fields   []string
+	Keywords []string
+}
+
+type schemaSlice struct {
+	schema *ast.Schema
+	// types maps the name of every type in the slice to the fields included for it. A nil field set
+	// means the whole type is included.
+	types   map[string]map[string]bool
+	unknown []string
+}
+
+// sliceSchema returns the part of the schema that is relevant to the request: the requested types,
+// every type reachable from them and the path of fields that leads to them from the query root.
+// An empty request returns everything reachable from the query root.
+func sliceSchema(schema *ast.Schema, request schemaSliceRequest) *schemaSlice {
+	slice := &schemaSlice{
+		schema: schema,
+		types:  map[string]map[string]bool{},
+	}
+
+	seeds := slice.seeds(request)
+	if len(request.Types)+len(request.Fields)+len(request.Keywords) == 0 {
+		seeds = []string{schema.Query.Name}
+	}
+
+	for _, seed := range seeds {
+		slice.includeReachable(seed)
+	}
+
+	parents := slice.queryParents()
+	for _, seed := range seeds {
+		slice.includePathFromQuery(seed, parents)
+	}
+
+	return slice
+}
+
+func (s *schemaSlice) seeds(request schemaSliceRequest) []string {
+	var seeds []string
+
+	for _, name := range request.Types {
+		definition := s.lookupType(name)
+		if definition == nil {
+			s.unknown = append(s.unknown, name)
+			continue
+		}
+		seeds = append(seeds, definition.Name)
+	}
+
+	for _, name := range request.Fields {
+		typeName, fieldName, qualified := strings.Cut(name, ".")
+		if !qualified {
+			typeName, fieldName = "", name
+		}
+
+		found := false
+		for _, definition := range s.schema.Types {
+			if definition.BuiltIn || (qualified && !strings.EqualFold(definition.Name, typeName)) {
+				continue
+			}
+			for _, field := range definition.Fields {
+				if strings.EqualFold(field.Name, fieldName) {
+					seeds = append(seeds, definition.Name, field.Type.Name())
+					found = true
+				}
+			}
+		}
+		if !found {
+			s.unknown = append(s.unknown, name)
+		}
+	}
+
+	for _, keyword := range request.Keywords {
+		keyword = strings.ToLower(keyword)
+
+		found := false
+		for _, definition := range s.schema.Types {
+			if definition.BuiltIn || strings.HasPrefix(definition.Name, "__") {
+				continue
+			}
+			if matchesKeyword(keyword, definition.Name, definition.Description) {
+				seeds = append(seeds, definition.Name)
+				found = true
+				continue
+			}
+			for _, field := range definition.Fields {
+				if matchesKeyword(keyword, field.Name, field.Description) {
+					seeds = append(seeds, definition.Name)
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			s.unknown = append(s.unknown, keyword)
+		}
+	}
+
+	sort.Strings(seeds)
+	return seeds
+}
+
+func (s *schemaSlice) includeReachable(name string) {
+	definition := s.schema.Types[name]
+	if definition == nil || definition.BuiltIn {
+		return
+	}
+	if fields, included := s.types[name]; included && fields == nil {
+		return
+	}
+	s.types[name] = nil
+
+	for _, field := range definition.Fields {
+		if strings.HasPrefix(field.Name, "__") {
+			continue
+		}
+		s.includeReachable(field.Type.Name())
+		for _, arg := range field.Arguments {
+			s.includeReachable(arg.Type.Name())
+		}
+	}
+
+	for _, possibleType := range s.schema.PossibleTypes[name] {
+		s.includeReachable(possibleType.Name)
+	}
+}
+
+type schemaParent struct {
+	typeName  string
+	fieldName string
+}
+
+// queryParents finds, for every output type, the field through which it is first reached from the
+// query root.
+func (s *schemaSlice) queryParents() map[string]schemaParent {
+	parents := map[string]schemaParent{}
+	queue := []string{s.schema.Query.Name}
+	visited := map[string]bool{s.schema.Query.Name: true}
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+
+		definition := s.schema.Types[name]
+		if definition == nil {
+			continue
+		}
+		for _, field := range definition.Fields {
+			fieldType := field.Type.Name()
+			if visited[fieldType] || strings.HasPrefix(field.Name, "__") {
+				continue
+			}
+			visited[fieldType] = true
+			parents[fieldType] = schemaParent{typeName: name, fieldName: field.Name}
+			queue = append(queue, fieldType)
+		}
+	}
+
+	return parents
+}
+
+func (s *schemaSlice) includePathFromQuery(name string, parents map[string]schemaParent) {
+	for {
+		parent, ok := parents[name]
+		if !ok {
+			return
+		}
+
+		fields, included := s.types[parent.typeName]
+		if !included {
+			fields = map[string]bool{}
+			s.types[parent.typeName] = fields
+		}
+		if fields != nil {
+			fields[parent.fieldName] = true
+		}
+
+		field := s.schema.Types[parent.typeName].Fields.ForName(parent.fieldName)
+		for _, arg := range field.Arguments {
+			s.includeReachable(arg.Type.Name())
+		}
+
+		name = parent.typeName
+	}
+}
+
+func (s *schemaSlice) lookupType(name string) *ast.Definition {
+	if definition, ok := s.schema.Types[name]; ok {
+		return definition
+	}
+	for typeName, definition := range s.schema.Types {
+		if strings.EqualFold(typeName, name) {
+			return definition
+		}
+	}
+	return nil
+}
+
+// SDL renders the slice as compact SDL with descriptions shortened to comments.
+func (s *schemaSlice) SDL() string {
+	names := make([]string, 0, len(s.types))
+	for name := range s.types {
+		if name != s.schema.Query.Name {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := s.types[s.schema.Query.Name]; ok {
+		names = append([]string{s.schema.Query.Name}, names...)
+	}
+
+	var sdl strings.Builder
+	for _, name := range names {
+		writeDefinitionSDL(&sdl, s.schema.Types[name], s.types[name])
+	}
+	return strings.TrimSpace(sdl.String())
+}
+
+func writeDefinitionSDL(sdl *strings.Builder, definition *ast.Definition, fields map[string]bool) {
+	if description := shortDescription(definition.Description); description != "" {
+		fmt.Fprintf(sdl, "# %s\n", description)
+	}
+
+	switch definition.Kind {
+	case ast.Scalar:
+		fmt.Fprintf(sdl, "scalar %s\n", definition.Name)
+
+	case ast.Union:
+		fmt.Fprintf(sdl, "union %s = %s\n", definition.Name, strings.Join(definition.Types, " | "))
+
+	case ast.Enum:
+		values := make([]string, 0, len(definition.EnumValues))
+		for _, value := range definition.EnumValues {
+			values = append(values, value.Name)
+		}
+		fmt.Fprintf(sdl, "enum %s { %s }\n", definition.Name, strings.Join(values, " "))
+
+	default:
+		keyword := kindKeyword(definition.Kind)
+		fmt.Fprintf(sdl, "%s %s", keyword, definition.Name)
+		if len(definition.Interfaces) > 0 {
+			fmt.Fprintf(sdl, " implements %s", strings.Join(definition.Interfaces, " & "))
+		}
+		sdl.WriteString(" {\n")
+
+		for _, field := range definition.Fields {
+			if strings.HasPrefix(field.Name, "__") || (fields != nil && !fields[field.Name]) {
+				continue
+			}
+
+			fmt.Fprintf(sdl, "  %s", field.Name)
+			if len(field.Arguments) > 0 {
+				args := make([]string, 0, len(field.Arguments))
+				for _, arg := range field.Arguments {
+					value := fmt.Sprintf("%s: %s", arg.Name, arg.Type)
+					if arg.DefaultValue != nil {
+						value += " = " + arg.DefaultValue.String()
+					}
+					args = append(args, value)
+				}
+				fmt.Fprintf(sdl, "(%s)", strings.Join(args, ", "))
+			}
+			fmt.Fprintf(sdl, ": %s", field.Type)
+			if field.DefaultValue != nil {
+				fmt.Fprintf(sdl, " = %s", field.DefaultValue.String())
+			}
+			if description := shortDescription(field.Description); description != "" {
+				fmt.Fprintf(sdl, " # %s", description)
+			}
+			sdl.WriteString("\n")
+		}
+		sdl.WriteString("}\n")
+	}
+	sdl.WriteString("\n")
+}
+
+// shortDescription keeps the first sentence of a description on a single line.
+func shortDescription(description string) string {
+	description = strings.TrimSpace(description)
+	if end := strings.Index(description, ". "); end >= 0 {
+		description = description[:end+1]
+	}
+	if end := strings.Index(description, "\n"); end >= 0 {
+		description = description[:end]
+	}
+	return strings.TrimSpace(description)
+}
+
+func matchesKeyword(keyword string, name string, description string) bool {
+	return strings.Contains(strings.ToLower(name), keyword) || strings.Contains(strings.ToLower(description), keyword)
+}
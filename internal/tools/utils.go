@@ -1,165 +1,149 @@
 package tools
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
-	"io/ioutil"
+	"fusion/internal/knowledge"
+	"fusion/internal/llm"
 	"log"
-	"net/http"
-	"time"
-	"trpc.group/trpc-go/trpc-a2a-go/taskmanager"
+	"strings"
 )
 
 type Tool interface {
-	GenerateToolSchema() *types.ToolMemberToolSpec
-	Call(toolCall *types.ContentBlockMemberToolUse) (*types.Message, error)
+	GenerateToolSchema() llm.ToolSpec
+	Call(ctx context.Context, toolCall *llm.ToolUseBlock) (*llm.ToolResultBlock, error)
 }
 
-var Tools map[string]Tool
+// NewDefaultRegistry registers the agent's tools. fetch_result is only registered when a result store
+// is given, and the operations of execute_query are not checked when the policy is nil.
+func NewDefaultRegistry(client *GraphQLClient, schemas *SchemaProvider, pagination PaginationLimits, policy *QueryPolicy, knowledgeIndex *knowledge.Index, results ResultStore, resultChunkSize int) *Registry {
+	registry := NewRegistry()
+
+	registry.MustRegister(NewQuerySchemaTool(schemas), Metadata{DisplayName: "Query Schema", Tags: []string{"graphql", "schema"}})
+	registry.MustRegister(NewExecuteQueryTool(client, schemas, pagination, policy), Metadata{DisplayName: "Execute Query", Tags: []string{"graphql", "assets"}})
+	registry.MustRegister(NewKnowledgeQueryTool(knowledgeIndex), Metadata{DisplayName: "Knowledge Query", Tags: []string{"knowledge"}})
+	registry.MustRegister(NewAssetDetailsTool(client, schemas), Metadata{DisplayName: "Asset Details", Tags: []string{"assets"}})
+	registry.MustRegister(NewUserInputTool(), Metadata{DisplayName: "User Input Required", Tags: []string{"conversation"}})
+	if results != nil {
+		registry.MustRegister(NewFetchResultTool(results, resultChunkSize), Metadata{DisplayName: "Fetch Result", Tags: []string{"conversation"}})
+	}
 
-func ToolConfig(handle taskmanager.TaskHandler, taskID string, contextID *string, token string) types.ToolConfiguration {
-	querySchemaTool := NewQuerySchemaTool()
-	querySchemaToolSchema := querySchemaTool.GenerateToolSchema()
+	return registry
+}
 
-	executeQueryTool := NewExecuteQueryTool(token)
-	executeQueryToolSchema := executeQueryTool.GenerateToolSchema()
+func stringParameter(toolCall *llm.ToolUseBlock, name string) (string, error) {
+	if toolCall.Input[name] == nil {
+		return "", fmt.Errorf("tool call failed. missing required parameter %q", name)
+	}
 
-	knowledgeQueryTool := NewKnowledgeQueryTool()
-	knowledgeQueryToolSchema := knowledgeQueryTool.GenerateToolSchema()
+	value, ok := toolCall.Input[name].(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("tool call failed. parameter %q must be a non-empty string", name)
+	}
 
-	assetDetailsTool := NewAssetDetailsTool(token)
-	assetDetailsToolSchema := assetDetailsTool.GenerateToolSchema()
+	return value, nil
+}
 
-	userInputTool := NewUserInputTool(handle, taskID, contextID)
-	userInputToolSchema := userInputTool.GenerateToolSchema()
+// stringListParameter reads an optional array of strings. A missing parameter returns nil.
+func stringListParameter(toolCall *llm.ToolUseBlock, name string) ([]string, error) {
+	if toolCall.Input[name] == nil {
+		return nil, nil
+	}
 
-	Tools = map[string]Tool{
-		querySchemaTool.Name:    querySchemaTool,
-		executeQueryTool.Name:   executeQueryTool,
-		knowledgeQueryTool.Name: knowledgeQueryTool,
-		assetDetailsTool.Name:   assetDetailsTool,
-		userInputTool.Name:      userInputTool,
+	items, ok := toolCall.Input[name].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("tool call failed. parameter %q must be an array of strings", name)
 	}
 
-	return types.ToolConfiguration{
-		Tools: []types.Tool{
-			querySchemaToolSchema,
-			executeQueryToolSchema,
-			knowledgeQueryToolSchema,
-			assetDetailsToolSchema,
-			userInputToolSchema,
-		},
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		value, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("tool call failed. parameter %q must be an array of strings", name)
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
 	}
-}
 
-type graphQLBody struct {
-	Query     string                 `json:"query"`
-	Variables map[string]interface{} `json:"variables,omitempty"`
+	return values, nil
 }
 
-func executeQuery(payload graphQLBody, token string) (string, error) {
+// boolParameter reads an optional boolean. A missing parameter returns false.
+func boolParameter(toolCall *llm.ToolUseBlock, name string) (bool, error) {
+	if toolCall.Input[name] == nil {
+		return false, nil
+	}
 
-	payloadJSON, err := json.Marshal(payload)
-	if err != nil {
-		fmt.Printf("Failed to marshal the query %s\n", err)
+	value, ok := toolCall.Input[name].(bool)
+	if !ok {
+		return false, fmt.Errorf("tool call failed. parameter %q must be a boolean", name)
 	}
 
-	bearer := "Bearer " + token
-	request, err := http.NewRequest(http.MethodPost, "https://stg.api.n-able.com/graphql", bytes.NewBuffer(payloadJSON))
-	request.Header.Add("Authorization", bearer)
-	request.Header.Add("Accept", "application/json")
-	request.Header.Add("Content-Type", "application/json")
+	return value, nil
+}
+
+// intParameter reads an optional positive integer. A missing parameter returns 0.
+func intParameter(toolCall *llm.ToolUseBlock, name string) (int, error) {
+	return intParameterAtLeast(toolCall, name, 1)
+}
 
-	client := &http.Client{Timeout: time.Second * 10}
+// intParameterAtLeast reads an optional integer of at least min. A missing parameter returns 0.
+func intParameterAtLeast(toolCall *llm.ToolUseBlock, name string, min int64) (int, error) {
+	if toolCall.Input[name] == nil {
+		return 0, nil
+	}
 
-	response, err := client.Do(request)
-	if err != nil {
-		fmt.Printf("The N-Query Client Request failed with error %s\n", err)
-		return "", err
+	var value int64
+	var err error
+	switch number := toolCall.Input[name].(type) {
+	case json.Number:
+		value, err = number.Int64()
+	case float64:
+		value = int64(number)
+	default:
+		err = errors.New("not a number")
+	}
+	if err != nil || value < min {
+		if min == 1 {
+			return 0, fmt.Errorf("tool call failed. parameter %q must be a positive integer", name)
+		}
+		return 0, fmt.Errorf("tool call failed. parameter %q must be an integer of at least %d", name, min)
 	}
-	defer response.Body.Close()
 
-	data, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		fmt.Printf("The N-Query reading response failed with error %s\n", err)
-		return "", err
+	return int(value), nil
+}
+
+func errorResult(toolCall *llm.ToolUseBlock, err error) *llm.ToolResultBlock {
+	return &llm.ToolResultBlock{
+		ToolUseID: toolCall.ID,
+		Text:      err.Error(),
+		IsError:   true,
 	}
+}
 
-	return string(data), nil
+// EstimateTokens gives a rough token count for text sent to the model, using the common
+// approximation of four characters per token.
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
 }
 
-func HandleToolUse(output types.ConverseOutput, messages *[]types.Message, token string) error {
-	switch v := output.(type) {
-	case *types.ConverseOutputMemberMessage:
-		*messages = append(*messages, v.Value)
-
-		for _, item := range v.Value.Content {
-			switch contentBlock := item.(type) {
-			case *types.ContentBlockMemberReasoningContent:
-				fmt.Printf("Handle Tool Use: Content Block Member Reasoning Content: %s\n", contentBlock.Value)
-			case *types.ContentBlockMemberText:
-				fmt.Printf("Handle Tool Use: Content Block Member Text: %s\n", contentBlock.Value)
-			case *types.ContentBlockMemberToolUse:
-				fmt.Printf("Handle Tool Use: Content Block Member Tool Use: %s\n", *contentBlock.Value.Name)
-
-				if *contentBlock.Value.Name == "query_schema" {
-					message, err := Tools["query_schema"].Call(contentBlock)
-					if err != nil {
-						fmt.Printf("Error invoking tool: %v\n", err)
-						return err
-					} else {
-						*messages = append(*messages, *message)
-					}
-				}
-
-				if *contentBlock.Value.Name == "execute_query" {
-					message, err := Tools["execute_query"].Call(contentBlock)
-					if err != nil {
-						fmt.Printf("Error invoking tool: %v\n", err)
-						return err
-					} else {
-						*messages = append(*messages, *message)
-					}
-				}
-
-				if *contentBlock.Value.Name == "knowledge_query" {
-					message, err := Tools["knowledge_query"].Call(contentBlock)
-					if err != nil {
-						fmt.Printf("Error invoking tool: %v\n", err)
-						return err
-					} else {
-						*messages = append(*messages, *message)
-					}
-				}
-
-				if *contentBlock.Value.Name == "asset_details" {
-					message, err := Tools["asset_details"].Call(contentBlock)
-					if err != nil {
-						fmt.Printf("Error invoking tool: %v\n", err)
-						return err
-					} else {
-						*messages = append(*messages, *message)
-					}
-				}
-
-				if *contentBlock.Value.Name == "user_input_required" {
-					message, err := Tools["user_input_required"].Call(contentBlock)
-					if err != nil {
-						fmt.Printf("Error invoking tool: %v\n", err)
-						return err
-					} else {
-						*messages = append(*messages, *message)
-					}
-				}
-			}
+func Contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
 		}
-	default:
-		fmt.Println("Response is nil or unknown type")
 	}
+	return false
+}
 
-	return nil
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length] + "..."
 }
 
 func PrintJSON(data interface{}) {